@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tomplus/otlprobe/probe"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// defaultAPILimit is the number of signals returned by /api/signals.
+const defaultAPILimit = 100
+
+// apiStreamBuffer is the number of signals queued for a slow stream client,
+// newer signals are dropped when it's full.
+const apiStreamBuffer = 256
+
+// API serves buffered signals as JSON and streams new ones as Server-Sent
+// Events, e.g. for scripts checking what was received.
+type API struct {
+	bucket probe.Bucket
+	filter *Query
+
+	mu          sync.Mutex
+	subscribers map[*apiSubscriber]struct{}
+}
+
+type apiSubscriber struct {
+	ch      chan *probe.Signal
+	dropped int
+}
+
+// jsonSignal is marshaled like a line of the jsonl output without the index,
+// or with properties grouped by sections (e.g. for the web UI).
+type jsonSignal struct {
+	signal   *probe.Signal
+	sections bool
+}
+
+type apiSectionSignal struct {
+	Kind      string       `json:"kind"`
+	Time      string       `json:"time"`
+	Summary   string       `json:"summary"`
+	Level     string       `json:"level,omitempty"`
+	Chartable bool         `json:"chartable,omitempty"`
+	Lint      int          `json:"lint,omitempty"`
+	Sections  []apiSection `json:"sections"`
+}
+
+type apiSection struct {
+	Name    string     `json:"name"`
+	Entries []apiEntry `json:"entries"`
+}
+
+// apiEntry is a property, typed values keep their JSON form and OTLP type.
+type apiEntry struct {
+	Name  string `json:"name"`
+	Value any    `json:"value"`
+	Type  string `json:"type,omitempty"`
+}
+
+func (j jsonSignal) MarshalJSON() ([]byte, error) {
+	if j.sections {
+		return json.Marshal(newAPISectionSignal(j.signal))
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	if err := writeSignalFields(&buf, j.signal); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// apiSpan is a row of the trace tree, missing parents have no signal and
+// span the time of their children.
+type apiSpan struct {
+	Depth  int         `json:"depth"`
+	SpanID string      `json:"span_id"`
+	Start  time.Time   `json:"start"`
+	End    time.Time   `json:"end"`
+	Orphan bool        `json:"orphan,omitempty"`
+	Signal *jsonSignal `json:"signal,omitempty"`
+}
+
+type apiTrace struct {
+	TraceID string    `json:"trace_id"`
+	Spans   []apiSpan `json:"spans"`
+	Missing int       `json:"missing"`
+}
+
+type apiSeries struct {
+	Series     string         `json:"series"`
+	Attributes map[string]any `json:"attributes"`
+	Resource   map[string]any `json:"resource"`
+	Rate       bool           `json:"rate"`
+	Points     []apiPoint     `json:"points"`
+}
+
+type apiPoint struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+func newAPISectionSignal(s *probe.Signal) apiSectionSignal {
+	res := apiSectionSignal{
+		Kind:      kindName(s.Kind),
+		Time:      signalTime(s),
+		Summary:   s.Summary(),
+		Level:     signalLevel(s),
+		Chartable: chartable(s),
+		Lint:      len(s.Lint),
+		Sections:  make([]apiSection, 0),
+	}
+	for _, p := range s.Properties() {
+		section := apiSection{Name: p.Name(), Entries: make([]apiEntry, 0)}
+		for _, e := range p.Entries() {
+			entry := apiEntry{Name: e.Name, Value: e.Text}
+			// NaN and infinity can't be encoded as JSON numbers
+			if e.Typed && !(e.Value.Type() == pcommon.ValueTypeDouble && (math.IsNaN(e.Value.Double()) || math.IsInf(e.Value.Double(), 0))) {
+				entry.Value, entry.Type = e.Value.AsRaw(), probe.TypeName(e.Value)
+			}
+			section.Entries = append(section.Entries, entry)
+		}
+		res.Sections = append(res.Sections, section)
+	}
+	return res
+}
+
+func newAPI(bucket probe.Bucket, filter *Query) *API {
+	api := API{
+		bucket:      bucket,
+		filter:      filter,
+		subscribers: make(map[*apiSubscriber]struct{}),
+	}
+	return &api
+}
+
+func (api *API) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/signals", api.signals)
+	mux.HandleFunc("DELETE /api/signals", api.clear)
+	mux.HandleFunc("GET /api/traces/{traceId}", api.trace)
+	mux.HandleFunc("GET /api/metrics/{name}/series", api.series)
+	mux.HandleFunc("GET /api/stream", api.stream)
+	return mux
+}
+
+// tee passes signals from the channel on, the ones matching the filter are
+// kept in the bucket of the API and published to the stream clients first.
+func (api *API) tee(ch chan *probe.Signal) chan *probe.Signal {
+	out := make(chan *probe.Signal)
+	go func() {
+		defer close(out)
+		for s := range ch {
+			if api.filter.match(s) {
+				api.bucket.Append(s)
+				api.publish(s)
+			}
+			out <- s
+		}
+	}()
+	return out
+}
+
+func (api *API) publish(s *probe.Signal) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	for sub := range api.subscribers {
+		select {
+		case sub.ch <- s:
+		default:
+			sub.dropped++
+		}
+	}
+}
+
+func (api *API) subscribe() *apiSubscriber {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	sub := apiSubscriber{ch: make(chan *probe.Signal, apiStreamBuffer)}
+	api.subscribers[&sub] = struct{}{}
+	return &sub
+}
+
+func (api *API) unsubscribe(sub *apiSubscriber) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	delete(api.subscribers, sub)
+}
+
+// takeDropped returns and resets the number of signals dropped for the client.
+func (api *API) takeDropped(sub *apiSubscriber) int {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	n := sub.dropped
+	sub.dropped = 0
+	return n
+}
+
+// requestSections returns true for requests of signals grouped by sections.
+func requestSections(r *http.Request) bool {
+	sections, _ := strconv.ParseBool(r.URL.Query().Get("sections"))
+	return sections
+}
+
+// requestQuery builds the query from the kind and q parameters.
+func requestQuery(r *http.Request) (*Query, error) {
+	text := r.URL.Query().Get("q")
+	if kind := r.URL.Query().Get("kind"); kind != "" {
+		if _, err := normalizeKind(kind); err != nil {
+			return nil, err
+		}
+		if text != "" {
+			text = "(" + text + ")"
+		}
+		text = "kind=" + kind + " " + text
+	}
+	return parseQuery(text)
+}
+
+func writeAPIResponse(w http.ResponseWriter, status int, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+	w.Write([]byte("\n"))
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	body, _ := json.Marshal(map[string]string{"error": err.Error()})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+	w.Write([]byte("\n"))
+}
+
+// signals returns buffered signals matching kind and q, the newest first.
+func (api *API) signals(w http.ResponseWriter, r *http.Request) {
+	q, err := requestQuery(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	limit := defaultAPILimit
+	if text := r.URL.Query().Get("limit"); text != "" {
+		limit, err = strconv.Atoi(text)
+		if err != nil || limit < 1 {
+			writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", text))
+			return
+		}
+	}
+	res := make([]jsonSignal, 0)
+	for i := 0; i < api.bucket.Len() && len(res) < limit; i++ {
+		if ok, s := api.bucket.Get(i); ok && q.match(s) {
+			res = append(res, jsonSignal{s, requestSections(r)})
+		}
+	}
+	writeAPIResponse(w, http.StatusOK, res)
+}
+
+func (api *API) clear(w http.ResponseWriter, r *http.Request) {
+	api.bucket.Clear()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// trace returns buffered spans of the trace in the order of the waterfall.
+func (api *API) trace(w http.ResponseWriter, r *http.Request) {
+	var traceID pcommon.TraceID
+	b, err := hex.DecodeString(r.PathValue("traceId"))
+	if err != nil || len(b) != len(traceID) {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid trace ID %q", r.PathValue("traceId")))
+		return
+	}
+	copy(traceID[:], b)
+	spans := traceSpans(api.bucket, traceID)
+	if len(spans) == 0 {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("trace %v not found", traceID))
+		return
+	}
+	res := apiTrace{TraceID: traceID.String(), Spans: make([]apiSpan, 0, len(spans))}
+	for _, row := range spanRows(buildSpanTree(spans)) {
+		span := apiSpan{
+			Depth:  row.depth,
+			SpanID: row.node.spanID.String(),
+			Start:  row.node.start.AsTime(),
+			End:    row.node.end.AsTime(),
+			Orphan: row.node.orphan,
+		}
+		if row.node.signal == nil {
+			res.Missing++
+		} else {
+			span.Signal = &jsonSignal{row.node.signal, requestSections(r)}
+		}
+		res.Spans = append(res.Spans, span)
+	}
+	writeAPIResponse(w, http.StatusOK, res)
+}
+
+// series returns buffered points of every series of the gauge or sum,
+// rate=true converts monotonic cumulative sums to rate per second.
+func (api *API) series(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	rate := false
+	if text := r.URL.Query().Get("rate"); text != "" {
+		var err error
+		if rate, err = strconv.ParseBool(text); err != nil {
+			writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid rate %q", text))
+			return
+		}
+	}
+	// grouped in one pass, the newest series first
+	res := make([]apiSeries, 0)
+	points := make([][]chartPoint, 0)
+	index := make(map[string]int)
+	for i := api.bucket.Len() - 1; i >= 0; i-- {
+		ok, s := api.bucket.Get(i)
+		if !ok || !chartable(s) || s.Metric.Name() != name {
+			continue
+		}
+		key := seriesKey(s)
+		j, ok := index[key]
+		if !ok {
+			j = len(res)
+			index[key] = j
+			dp, _ := probe.NumberDataPoint(s)
+			res = append(res, apiSeries{
+				Series:     key,
+				Attributes: dp.Attributes().AsRaw(),
+				Resource:   s.Resource.Attributes().AsRaw(),
+				Rate:       rate && isMonotonicCumulative(s.Metric),
+				Points:     make([]apiPoint, 0),
+			})
+			points = append(points, make([]chartPoint, 0))
+		}
+		if p, ok := newChartPoint(s); ok {
+			points[j] = append(points[j], p)
+		}
+	}
+	for j := range res {
+		sortChartPoints(points[j])
+		if res[j].Rate {
+			points[j] = ratePoints(points[j])
+		}
+		for _, p := range points[j] {
+			res[j].Points = append(res[j].Points, apiPoint{Time: p.time.AsTime(), Value: p.value})
+		}
+	}
+	if len(res) == 0 {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("no gauge or sum %q", name))
+		return
+	}
+	writeAPIResponse(w, http.StatusOK, res)
+}
+
+// stream sends new signals matching kind and q as "signal" events. When the
+// client is too slow, the number of skipped signals is sent as a "dropped"
+// event.
+func (api *API) stream(w http.ResponseWriter, r *http.Request) {
+	q, err := requestQuery(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	sections := requestSections(r)
+	sub := api.subscribe()
+	defer api.unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case s := <-sub.ch:
+			if n := api.takeDropped(sub); n > 0 {
+				fmt.Fprintf(w, "event: dropped\ndata: %d\n\n", n)
+			}
+			if !q.match(s) {
+				continue
+			}
+			data, err := jsonSignal{s, sections}.MarshalJSON()
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: signal\ndata: %s\n\n", data)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tomplus/otlprobe/probe"
+)
+
+func newTestAPI(t *testing.T) (*API, *httptest.Server) {
+	bucket := probe.NewBucketFixedSize(20)
+	for _, s := range newOutputSignals() {
+		bucket.Append(s)
+	}
+	for _, s := range []*probe.Signal{newTestSpan(1, 0, 0, 100), newTestSpan(2, 1, 10, 20), newTestSpan(4, 3, 30, 40)} {
+		bucket.Append(s)
+	}
+	api := newAPI(bucket, &Query{})
+	srv := httptest.NewServer(api.handler())
+	t.Cleanup(srv.Close)
+	return api, srv
+}
+
+func getJSON(t *testing.T, url string, v any) int {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode
+}
+
+func TestAPISignals(t *testing.T) {
+
+	api, srv := newTestAPI(t)
+
+	var signals []map[string]any
+	if code := getJSON(t, srv.URL+"/api/signals", &signals); code != http.StatusOK || len(signals) != 6 || signals[0]["kind"] != "trace" {
+		t.Errorf("invalid signals => %v, %v", code, signals)
+	}
+	signals = nil
+	getJSON(t, srv.URL+"/api/signals?kind=metric&q=value>10&limit=5", &signals)
+	if len(signals) != 1 || signals[0]["DataPoint.Value"] != "11" {
+		t.Errorf("invalid filtered signals => %v", signals)
+	}
+	signals = nil
+	getJSON(t, srv.URL+"/api/signals?kind=trace&limit=2", &signals)
+	if len(signals) != 2 || signals[1]["Span.Name"] != "b" {
+		t.Errorf("invalid limited signals => %v", signals)
+	}
+
+	var sectioned []apiSectionSignal
+	getJSON(t, srv.URL+"/api/signals?kind=log&sections=true", &sectioned)
+	if len(sectioned) != 1 || sectioned[0].Sections[0].Name != "Record" {
+		t.Errorf("invalid signals with sections => %+v", sectioned)
+	}
+	for _, e := range sectioned[0].Sections[0].Entries {
+		if e.Name == "Attributes.attempt" && (e.Value != float64(3) || e.Type != "int") {
+			t.Errorf("invalid typed entry => %+v", e)
+		}
+	}
+
+	var apiErr map[string]string
+	for _, query := range []string{"kind=event", "q=(name=x", "limit=0"} {
+		if code := getJSON(t, srv.URL+"/api/signals?"+query, &apiErr); code != http.StatusBadRequest || apiErr["error"] == "" {
+			t.Errorf("invalid query %v should fail => %v, %v", query, code, apiErr)
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, srv.URL+"/api/signals", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil || resp.StatusCode != http.StatusNoContent || api.bucket.Len() != 0 {
+		t.Errorf("signals should be cleared => %v, %v", resp, err)
+	}
+
+}
+
+func TestAPITraceAndSeries(t *testing.T) {
+
+	_, srv := newTestAPI(t)
+
+	var apiErr map[string]string
+	var raw struct {
+		Spans []struct {
+			Depth  int            `json:"depth"`
+			Signal map[string]any `json:"signal"`
+		} `json:"spans"`
+		Missing int `json:"missing"`
+	}
+	if code := getJSON(t, srv.URL+"/api/traces/01000000000000000000000000000000", &raw); code != http.StatusOK ||
+		len(raw.Spans) != 4 || raw.Missing != 1 || raw.Spans[1].Depth != 1 || raw.Spans[1].Signal["Span.Name"] != "b" || raw.Spans[2].Signal != nil {
+		t.Errorf("invalid trace => %v, %+v", code, raw)
+	}
+	if code := getJSON(t, srv.URL+"/api/traces/02000000000000000000000000000000", &apiErr); code != http.StatusNotFound {
+		t.Errorf("unknown trace should not be found => %v", code)
+	}
+	if code := getJSON(t, srv.URL+"/api/traces/xyz", &apiErr); code != http.StatusBadRequest {
+		t.Errorf("invalid trace ID should fail => %v", code)
+	}
+
+	var series []apiSeries
+	if code := getJSON(t, srv.URL+"/api/metrics/requests/series", &series); code != http.StatusOK ||
+		len(series) != 1 || series[0].Series != "requests{}" || len(series[0].Points) != 2 || series[0].Points[1].Value != 11 {
+		t.Errorf("invalid series => %v, %+v", code, series)
+	}
+	if code := getJSON(t, srv.URL+"/api/metrics/other/series", &apiErr); code != http.StatusNotFound {
+		t.Errorf("unknown metric should not be found => %v", code)
+	}
+
+}
+
+func TestAPIStream(t *testing.T) {
+
+	api, srv := newTestAPI(t)
+	resp, err := http.Get(srv.URL + "/api/stream?kind=log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("invalid content type => %v", resp.Header.Get("Content-Type"))
+	}
+
+	ch := make(chan *probe.Signal)
+	out := api.tee(ch)
+	go func() {
+		for range out {
+		}
+	}()
+	for _, s := range newOutputSignals() {
+		ch <- s
+	}
+	close(ch)
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	expected := []string{"event: signal", `data: {"kind":"log"`}
+	for i := 0; i < len(expected); i++ {
+		select {
+		case line := <-lines:
+			if !strings.HasPrefix(line, expected[i]) {
+				t.Errorf("invalid event line %d => %v", i, line)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event received")
+		}
+	}
+
+}
+
+func TestAPITee(t *testing.T) {
+
+	// signals passing the startup filter are kept whatever the consumer does
+	filter, _ := parseQuery("kind:log")
+	api := newAPI(probe.NewBucketFixedSize(10), filter)
+	ch := make(chan *probe.Signal)
+	out := api.tee(ch)
+	go func() {
+		for _, s := range newOutputSignals() {
+			ch <- s
+		}
+		close(ch)
+	}()
+	n := 0
+	for range out {
+		n++
+	}
+	if n != 3 || api.bucket.Len() != 1 {
+		t.Errorf("invalid tee => %d passed, %d kept", n, api.bucket.Len())
+	}
+
+}
@@ -1,35 +1,55 @@
 package main
 
 import (
+	"fmt"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 	"unicode"
 	"unicode/utf8"
 
 	"github.com/gdamore/tcell/v2"
+	"github.com/tomplus/otlprobe/probe"
 )
 
 type Browser struct {
+	// mu guards the state shared by the event loop and the goroutine
+	// receiving signals, methods called from outside take it
+	mu sync.Mutex
+
 	screen      tcell.Screen
-	bucket      Bucket
+	bucket      probe.Bucket
 	cursor      int
 	width       int
 	height      int
 	follow      bool
 	inputFilter bool
 	filter      string
+	filterErr   string
+	query       *Query
 
-	ch chan *Signal
+	ch      chan *probe.Signal
+	message atomic.Pointer[string]
+	// hold leaves signals in the channel while the browser doesn't follow,
+	// so the sender (e.g. reading of files) waits instead of losing them
+	hold bool
 
-	hb    *HeartbeatWidget
-	popUp *PopUp
+	hb         *HeartbeatWidget
+	popUp      *PopUp
+	faultPanel *FaultPanel
+	waterfall  *Waterfall
+	chart      *Chart
 
 	rowStyle             tcell.Style
 	rowSelectedStyle     tcell.Style
 	statusStyle          tcell.Style
 	statusHighlightStyle tcell.Style
+	messageStyle         tcell.Style
 }
 
-func newBrowser(screen tcell.Screen, bucket Bucket, filter string, ch chan *Signal) *Browser {
+func newBrowser(screen tcell.Screen, bucket probe.Bucket, filter *Query, ch chan *probe.Signal, faults *probe.FaultInjector) *Browser {
 	w, h := screen.Size()
 	b := Browser{
 		screen:               screen,
@@ -38,26 +58,43 @@ func newBrowser(screen tcell.Screen, bucket Bucket, filter string, ch chan *Sign
 		width:                w,
 		height:               h,
 		follow:               true,
-		filter:               filter,
+		filter:               filter.String(),
+		query:                filter,
 		ch:                   ch,
 		hb:                   newHeartbeatWidget(screen),
 		popUp:                newPopUp(screen),
+		faultPanel:           newFaultPanel(screen, faults),
 		rowStyle:             tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack),
 		rowSelectedStyle:     tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite).Bold(true),
 		statusStyle:          tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorLightCyan),
 		statusHighlightStyle: tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorDarkCyan),
+		messageStyle:         tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorDarkRed),
 	}
 
+	// views are used by the goroutine, e.g. chart.visible
+	b.waterfall = newWaterfall(screen, b.popUp)
+	b.chart = newChart(screen)
+
 	go func() {
 		for c := range b.ch {
 			b.hb.tick()
-			if b.follow {
-				if strings.Contains(c.description, b.filter) || strings.Contains(c.summary, b.filter) {
-					bucket.append(c)
+			b.mu.Lock()
+			for b.hold && !b.follow && !b.chart.visible {
+				b.mu.Unlock()
+				time.Sleep(100 * time.Millisecond)
+				b.mu.Lock()
+			}
+			if b.follow || b.chart.visible {
+				if b.query.match(c) {
+					bucket.Append(c)
+					if !b.follow {
+						// keep the same row selected
+						b.cursor = min(b.cursor+1, bucket.Len()-1)
+					}
 					b.refresh()
 				}
 			}
-
+			b.mu.Unlock()
 		}
 	}()
 
@@ -65,6 +102,8 @@ func newBrowser(screen tcell.Screen, bucket Bucket, filter string, ch chan *Sign
 }
 
 func (browser *Browser) resize() {
+	browser.mu.Lock()
+	defer browser.mu.Unlock()
 	w, h := screen.Size()
 	if w != browser.width || h != browser.height {
 		browser.width = w
@@ -76,42 +115,44 @@ func (browser *Browser) resize() {
 
 func (browser *Browser) drawRow(y int, style tcell.Style, highlight tcell.Style, text string) {
 
-	var hl []int = make([]int, browser.width)
-	if browser.filter != "" {
+	// matches are found by bytes, the row is drawn by runes
+	hl := make([]bool, len(text))
+	for _, term := range browser.query.terms() {
+		if term == "" {
+			continue
+		}
 		p := 0
 		for {
-			f := strings.Index(text[p:], browser.filter)
+			f := strings.Index(text[p:], term)
 			if f == -1 {
 				break
 			}
 			p += f
-			for i := 0; i < len(browser.filter); i++ {
-				if p > browser.width {
-					// match in invisible part, mark last character
-					// and escape from loops
-					hl[browser.width-1] = 1
-					p = len(text)
-					break
-				}
-				hl[p] = 1
-				p++
+			for end := p + len(term); p < end; p++ {
+				hl[p] = true
 			}
 		}
 	}
 
-	for i, col := 0, 0; col < browser.width; col++ {
-		r := ' '
+	i := 0
+	last := ' '
+	for col := 0; col < browser.width; col++ {
+		r, s := ' ', style
 		if i < len(text) {
-			var s int
-			r, s = utf8.DecodeRuneInString(text[i:])
-			i += s
-		}
-		s := style
-		if hl[col] == 1 {
-			s = highlight
+			var size int
+			r, size = utf8.DecodeRuneInString(text[i:])
+			if hl[i] {
+				s = highlight
+			}
+			i += size
 		}
+		last = r
 		browser.screen.SetContent(col, y, r, nil, s)
 	}
+	if browser.width > 0 && slices.Contains(hl[i:], true) {
+		// match in invisible part, mark last character
+		browser.screen.SetContent(browser.width-1, y, last, nil, highlight)
+	}
 }
 
 func (browser *Browser) drawText(x int, y int, style tcell.Style, text string) {
@@ -134,13 +175,30 @@ func (browser *Browser) refreshStatusBar() {
 		filter += " [" + browser.filter + "]"
 	}
 
-	menu := []string{"↑↓", "select", "Enter", "details", "Esc", "exit", "Shift+F", "follow", "/", filter}
+	faults := "faults"
+	if browser.faultPanel.faults.Active() {
+		faults += " [on]"
+	}
+
+	menu := []string{"↑↓", "select", "Enter", "details", "Shift+W", "waterfall", "Shift+C", "chart", "Esc", "exit", "Shift+F", "follow", "/", filter, "Shift+X", faults}
 	if browser.follow {
-		menu = []string{"↑↓", "stop & select", "Esc", "stop following", "/", filter}
+		menu = []string{"↑↓", "stop & select", "Esc", "stop following", "/", filter, "Shift+X", faults}
 	}
 	if browser.inputFilter {
 		menu = []string{"Find", browser.filter}
 	}
+	if browser.waterfall.visible {
+		menu = []string{"↑↓", "move", "Enter", "details", "Esc", "close"}
+	}
+	if browser.chart.visible {
+		menu = []string{"Esc", "close"}
+		if browser.chart.monotonic {
+			menu = []string{"Shift+R", "rate", "Esc", "close"}
+		}
+	}
+	if browser.popUp.visible {
+		menu = []string{"↑↓", "move", "Enter", "expand/collapse", "←→", "collapse/expand", "Esc", "close"}
+	}
 
 	col := 4
 	for i := 0; i < len(menu); i += 2 {
@@ -156,13 +214,79 @@ func (browser *Browser) refreshStatusBar() {
 		browser.screen.HideCursor()
 	}
 
+	msg := browser.message.Load()
+	if browser.filterErr != "" {
+		msg = &browser.filterErr
+	}
+	if msg != nil && col < browser.width {
+		text := " " + *msg + " "
+		browser.drawText(col, browser.height-1, browser.messageStyle, text)
+		col += utf8.RuneCountInString(text)
+	}
+
 	if col < browser.width {
 		browser.drawText(col, browser.height-1, browser.statusStyle, strings.Repeat(" ", browser.width-col))
 	}
 
+	stats := fmt.Sprintf(" %d received ", browser.bucket.Counter())
+	if ev := browser.bucket.Evicted(); ev.Total() > 0 {
+		stats = fmt.Sprintf(" %d received, %d evicted (%v) ", browser.bucket.Counter(), ev.Total(), ev)
+	}
+	if x := browser.width - utf8.RuneCountInString(stats); x > col {
+		browser.drawText(x, browser.height-1, browser.statusHighlightStyle, stats)
+	}
+
+}
+
+// applyFilter compiles the filter, the previous query is kept if it's invalid.
+func (browser *Browser) applyFilter() {
+	q, err := parseQuery(browser.filter)
+	if err != nil {
+		browser.filterErr = err.Error()
+		return
+	}
+	browser.filterErr = ""
+	browser.query = q
+}
+
+// showMessage displays the message (e.g. an error) in the status bar.
+func (browser *Browser) showMessage(msg string) {
+	browser.mu.Lock()
+	defer browser.mu.Unlock()
+	browser.setMessage(msg)
+}
+
+func (browser *Browser) setMessage(msg string) {
+	browser.message.Store(&msg)
+	browser.refresh()
+}
+
+// setHold leaves signals in the channel while the browser doesn't follow,
+// see hold.
+func (browser *Browser) setHold(hold bool) {
+	browser.mu.Lock()
+	defer browser.mu.Unlock()
+	browser.hold = hold
+}
+
+// show draws the whole screen, e.g. when the browser is started.
+func (browser *Browser) show() {
+	browser.mu.Lock()
+	defer browser.mu.Unlock()
+	browser.refresh()
 }
 
 func (browser *Browser) eventKey(ev *tcell.EventKey) bool {
+	browser.mu.Lock()
+	defer browser.mu.Unlock()
+
+	if browser.faultPanel.visible {
+		h := browser.faultPanel.eventKey(ev)
+		if h {
+			browser.refresh()
+		}
+		return h
+	}
 
 	if browser.popUp.visible {
 		h := browser.popUp.eventKey(ev)
@@ -172,6 +296,22 @@ func (browser *Browser) eventKey(ev *tcell.EventKey) bool {
 		return h
 	}
 
+	if browser.chart.visible {
+		h := browser.chart.eventKey(ev)
+		if h {
+			browser.refresh()
+		}
+		return h
+	}
+
+	if browser.waterfall.visible {
+		h := browser.waterfall.eventKey(ev)
+		if h {
+			browser.refresh()
+		}
+		return h
+	}
+
 	if ev.Key() == tcell.KeyDown {
 		if browser.cursor > 0 {
 			browser.cursor--
@@ -182,7 +322,7 @@ func (browser *Browser) eventKey(ev *tcell.EventKey) bool {
 		browser.refresh()
 		return true
 	} else if ev.Key() == tcell.KeyUp {
-		rng := browser.bucket.len() - 1
+		rng := browser.bucket.Len() - 1
 		if browser.height-2 < rng {
 			rng = browser.height - 2
 		}
@@ -194,6 +334,28 @@ func (browser *Browser) eventKey(ev *tcell.EventKey) bool {
 		browser.follow = false
 		browser.refresh()
 		return true
+	} else if ev.Rune() == 'X' && !browser.inputFilter {
+		browser.faultPanel.show()
+		browser.refresh()
+		return true
+	} else if ev.Rune() == 'W' && !browser.inputFilter && browser.cursor != -1 {
+		ok, data := browser.bucket.Get(browser.cursor)
+		if ok && data.Kind == probe.TRACE {
+			browser.waterfall.show(browser.bucket, data)
+			browser.refresh()
+		} else {
+			browser.setMessage("select a span to show its trace")
+		}
+		return true
+	} else if ev.Rune() == 'C' && !browser.inputFilter && browser.cursor != -1 {
+		ok, data := browser.bucket.Get(browser.cursor)
+		if ok && chartable(data) {
+			browser.chart.show(browser.bucket, data)
+			browser.refresh()
+		} else {
+			browser.setMessage("select a gauge or sum to show its chart")
+		}
+		return true
 	} else if ev.Rune() == 'F' && !browser.follow {
 		browser.follow = true
 		browser.cursor = -1
@@ -220,19 +382,21 @@ func (browser *Browser) eventKey(ev *tcell.EventKey) bool {
 			browser.refresh()
 			return true
 		} else if browser.cursor != -1 {
-			ok, data := browser.bucket.get(browser.cursor)
+			ok, data := browser.bucket.Get(browser.cursor)
 			if ok {
-				browser.popUp.show(data.properties)
+				browser.popUp.show(data.Properties())
 			}
 		}
 	} else if ev.Key() == tcell.KeyBackspace2 && browser.inputFilter {
 		if len(browser.filter) > 0 {
 			browser.filter = browser.filter[0 : len(browser.filter)-1]
+			browser.applyFilter()
 			browser.refresh()
 		}
 
 	} else if !unicode.IsControl(ev.Rune()) && browser.inputFilter {
 		browser.filter += string(ev.Rune())
+		browser.applyFilter()
 		browser.refresh()
 		return true
 	}
@@ -241,22 +405,31 @@ func (browser *Browser) eventKey(ev *tcell.EventKey) bool {
 
 func (browser *Browser) refresh() {
 
-	browser.popUp.refresh()
-
-	if !browser.popUp.visible {
+	if browser.popUp.visible {
+		browser.popUp.refresh()
+	} else if browser.waterfall.visible {
+		browser.waterfall.refresh()
+	} else if browser.chart.visible {
+		browser.chart.refresh()
+	} else {
 		for i, j := 0, browser.height-2; j >= 0; j-- {
 			style := browser.rowStyle
-			ok, val := bucket.get(i)
+			ok, val := browser.bucket.Get(i)
 			if ok {
 				if i == browser.cursor {
 					style = browser.rowSelectedStyle
 				}
-				browser.drawRow(j, style, browser.rowSelectedStyle, val.summary)
+				text := val.Summary()
+				if len(val.Lint) > 0 {
+					text = lintMarker + text
+				}
+				browser.drawRow(j, style, browser.rowSelectedStyle, text)
 			} else {
 				browser.drawRow(j, style, browser.rowSelectedStyle, "")
 			}
 			i++
 		}
+		browser.faultPanel.refresh()
 	}
 
 	browser.refreshStatusBar()
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/tomplus/otlprobe/probe"
+)
+
+func TestBrowserConcurrentSignals(t *testing.T) {
+
+	s := tcell.NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+	// not finalized, the heartbeat keeps drawing
+	s.SetSize(80, 25)
+	screen = s
+
+	filter, _ := parseQuery("")
+	bucket := probe.NewBucketFixedSize(10)
+	ch := make(chan *probe.Signal)
+	browser := newBrowser(s, bucket, filter, ch, probe.NewFaultInjector())
+	browser.show()
+
+	// keys are handled while signals are received
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			probe.TraceSignals(newTestTraces().Traces(), func(sig *probe.Signal) error {
+				ch <- sig
+				return nil
+			})
+		}
+	}()
+	keys := []*tcell.EventKey{
+		tcell.NewEventKey(tcell.KeyRune, '/', tcell.ModNone),
+		tcell.NewEventKey(tcell.KeyRune, 'P', tcell.ModNone),
+		tcell.NewEventKey(tcell.KeyBackspace2, 0, tcell.ModNone),
+		tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone),
+		tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone),
+		tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone),
+		tcell.NewEventKey(tcell.KeyRune, 'F', tcell.ModNone),
+	}
+	for i := 0; i < 7*50; i++ {
+		browser.eventKey(keys[i%len(keys)])
+	}
+	browser.showMessage("done")
+	<-done
+
+	// the second signal is received when the first one was handled
+	for i := 0; i < 2; i++ {
+		probe.TraceSignals(newTestTraces().Traces(), func(sig *probe.Signal) error {
+			ch <- sig
+			return nil
+		})
+	}
+	if bucket.Len() == 0 {
+		t.Errorf("signals should be received while following")
+	}
+
+}
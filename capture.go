@@ -0,0 +1,443 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tomplus/otlprobe/probe"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+)
+
+// Capture files are segments named capture-<sequence>.otlp, a segment starts
+// with the magic "OTLPROBE" and the format version (uint16) followed by
+// records:
+//
+//	length    uint32  length of the payload
+//	checksum  uint32  CRC-32C of kind, received and payload
+//	kind      uint8   1 logs, 2 metrics, 3 traces
+//	received  int64   receive time, Unix nanoseconds
+//	payload           OTLP protobuf export request
+//
+// Integers are big endian. A record torn by a crash fails the checksum or
+// ends prematurely, reading of the segment stops there.
+const (
+	captureMagic      = "OTLPROBE"
+	captureVersion    = 1
+	captureHeaderSize = len(captureMagic) + 2
+	recordHeaderSize  = 17
+	maxRecordSize     = 1 << 30
+
+	defaultCaptureSegmentSize = 64 << 20
+)
+
+var (
+	errCaptureFormat = errors.New("not an otlprobe capture file")
+	crcTable         = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// Capture appends received export requests to segment files in the directory,
+// a new segment is started when the current one would exceed segmentSize and
+// the oldest segments are removed when all of them exceed maxSize (zero means
+// no limit). It's safe for concurrent use.
+type Capture struct {
+	mu          sync.Mutex
+	dir         string
+	segmentSize int64
+	maxSize     int64
+	file        *os.File
+	seq         int
+	size        int64
+	errs        chan error
+
+	failed atomic.Uint64
+}
+
+// CaptureRecord is a single export request read from the capture.
+type CaptureRecord struct {
+	kind     probe.KindSignal
+	received time.Time
+	payload  []byte
+}
+
+// newCapture always starts a new segment, so a segment torn by a crash is
+// never appended to.
+func newCapture(dir string, segmentSize int64, maxSize int64) (*Capture, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	segments, err := captureSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	c := Capture{
+		dir:         dir,
+		segmentSize: segmentSize,
+		maxSize:     maxSize,
+		errs:        make(chan error, 100),
+	}
+	if len(segments) > 0 {
+		c.seq = segments[len(segments)-1].seq
+	}
+	if err := c.rotate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+type captureSegment struct {
+	path string
+	seq  int
+}
+
+// captureSegments returns segments in the directory from the oldest one.
+func captureSegments(dir string) ([]captureSegment, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "capture-*.otlp"))
+	if err != nil {
+		return nil, err
+	}
+	segments := make([]captureSegment, 0, len(paths))
+	for _, path := range paths {
+		var seq int
+		if _, err := fmt.Sscanf(filepath.Base(path), "capture-%d.otlp", &seq); err != nil {
+			continue
+		}
+		segments = append(segments, captureSegment{path: path, seq: seq})
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].seq < segments[j].seq
+	})
+	return segments, nil
+}
+
+func (c *Capture) rotate() error {
+	if c.file != nil {
+		if err := c.closeSegment(); err != nil {
+			return err
+		}
+	}
+	c.seq++
+	path := filepath.Join(c.dir, fmt.Sprintf("capture-%08d.otlp", c.seq))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	header := make([]byte, captureHeaderSize)
+	copy(header, captureMagic)
+	binary.BigEndian.PutUint16(header[len(captureMagic):], captureVersion)
+	if _, err := f.Write(header); err != nil {
+		f.Close()
+		return err
+	}
+	c.file = f
+	c.size = int64(captureHeaderSize)
+	return c.prune()
+}
+
+// prune removes the oldest segments while the capture exceeds maxSize,
+// the current segment is always kept.
+func (c *Capture) prune() error {
+	if c.maxSize <= 0 {
+		return nil
+	}
+	segments, err := captureSegments(c.dir)
+	if err != nil {
+		return err
+	}
+	sizes := make([]int64, len(segments))
+	total := int64(0)
+	for i, s := range segments {
+		if fi, err := os.Stat(s.path); err == nil {
+			sizes[i] = fi.Size()
+			total += sizes[i]
+		}
+	}
+	for i, s := range segments {
+		if total <= c.maxSize || s.seq == c.seq {
+			break
+		}
+		if err := os.Remove(s.path); err != nil {
+			return err
+		}
+		total -= sizes[i]
+	}
+	return nil
+}
+
+func (c *Capture) closeSegment() error {
+	err := c.file.Sync()
+	if cerr := c.file.Close(); err == nil {
+		err = cerr
+	}
+	c.file = nil
+	return err
+}
+
+// write appends the record with a single write call, so a crash of otlprobe
+// (not of the system) doesn't tear it.
+func (c *Capture) write(kind probe.KindSignal, received time.Time, payload []byte) error {
+	if len(payload) > maxRecordSize {
+		return fmt.Errorf("request of %d bytes is too large to capture", len(payload))
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.file == nil {
+		return errors.New("capture is closed")
+	}
+	size := int64(recordHeaderSize + len(payload))
+	if c.size > int64(captureHeaderSize) && c.size+size > c.segmentSize {
+		if err := c.rotate(); err != nil {
+			return err
+		}
+	}
+	buf := make([]byte, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(buf[0:], uint32(len(payload)))
+	buf[8] = captureKind(kind)
+	binary.BigEndian.PutUint64(buf[9:], uint64(received.UnixNano()))
+	copy(buf[recordHeaderSize:], payload)
+	binary.BigEndian.PutUint32(buf[4:], crc32.Checksum(buf[8:], crcTable))
+	n, err := c.file.Write(buf)
+	c.size += int64(n)
+	return err
+}
+
+// captureKind returns code of the kind stored in the file, it doesn't depend
+// on the values of KindSignal.
+func captureKind(kind probe.KindSignal) byte {
+	switch kind {
+	case probe.LOG:
+		return 1
+	case probe.METRIC:
+		return 2
+	case probe.TRACE:
+		return 3
+	}
+	return 0
+}
+
+func signalKind(code byte) (probe.KindSignal, bool) {
+	switch code {
+	case 1:
+		return probe.LOG, true
+	case 2:
+		return probe.METRIC, true
+	case 3:
+		return probe.TRACE, true
+	}
+	return 0, false
+}
+
+// HandleMetrics appends the request to the current segment, Capture is
+// a RequestHandler of the server.
+func (c *Capture) HandleMetrics(request pmetricotlp.ExportRequest) {
+	payload, err := request.MarshalProto()
+	if err == nil {
+		err = c.write(probe.METRIC, time.Now(), payload)
+	}
+	if err != nil {
+		c.report(err)
+	}
+}
+
+func (c *Capture) HandleLogs(request plogotlp.ExportRequest) {
+	payload, err := request.MarshalProto()
+	if err == nil {
+		err = c.write(probe.LOG, time.Now(), payload)
+	}
+	if err != nil {
+		c.report(err)
+	}
+}
+
+func (c *Capture) HandleTraces(request ptraceotlp.ExportRequest) {
+	payload, err := request.MarshalProto()
+	if err == nil {
+		err = c.write(probe.TRACE, time.Now(), payload)
+	}
+	if err != nil {
+		c.report(err)
+	}
+}
+
+// report passes the error to the consumer of errors() without blocking,
+// if nobody keeps up the error is only counted.
+func (c *Capture) report(err error) {
+	n := c.failed.Add(1)
+	select {
+	case c.errs <- fmt.Errorf("capture: %w (%d failed)", err, n):
+	default:
+	}
+}
+
+// errors returns channel with capture errors.
+func (c *Capture) errors() <-chan error {
+	return c.errs
+}
+
+func (c *Capture) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.file == nil {
+		return nil
+	}
+	return c.closeSegment()
+}
+
+// readCapture calls fn for every record in the directory from the oldest one.
+func readCapture(dir string, fn func(r CaptureRecord) error) error {
+	segments, err := captureSegments(dir)
+	if err != nil {
+		return err
+	}
+	for _, s := range segments {
+		if err := readSegment(s.path, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSegment stops without error at the first torn or corrupted record.
+func readSegment(path string, fn func(r CaptureRecord) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	rd := bufio.NewReader(f)
+	header := make([]byte, captureHeaderSize)
+	if _, err := io.ReadFull(rd, header); errors.Is(err, io.EOF) {
+		// created just before a crash
+		return nil
+	} else if err != nil || string(header[:len(captureMagic)]) != captureMagic {
+		return fmt.Errorf("%s: %w", path, errCaptureFormat)
+	}
+	if v := binary.BigEndian.Uint16(header[len(captureMagic):]); v != captureVersion {
+		return fmt.Errorf("%s: unsupported capture version %d", path, v)
+	}
+
+	for {
+		header := make([]byte, recordHeaderSize)
+		if _, err := io.ReadFull(rd, header); err != nil {
+			return nil
+		}
+		length := binary.BigEndian.Uint32(header[0:])
+		checksum := binary.BigEndian.Uint32(header[4:])
+		if length > maxRecordSize {
+			return nil
+		}
+		// payload is not reused, consumers may keep it
+		record := make([]byte, recordHeaderSize+int(length))
+		copy(record, header)
+		if _, err := io.ReadFull(rd, record[recordHeaderSize:]); err != nil {
+			return nil
+		}
+		if crc32.Checksum(record[8:], crcTable) != checksum {
+			return nil
+		}
+		kind, ok := signalKind(record[8])
+		if !ok {
+			return nil
+		}
+		r := CaptureRecord{
+			kind:     kind,
+			received: time.Unix(0, int64(binary.BigEndian.Uint64(record[9:]))),
+			payload:  record[recordHeaderSize:],
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+}
+
+// decode unmarshals the request of the record.
+func (r CaptureRecord) decode() (probe.OTLPData, error) {
+	d := probe.OTLPData{Kind: r.kind, Received: r.received}
+	var err error
+	switch r.kind {
+	case probe.METRIC:
+		d.Metrics, err = (&pmetric.ProtoUnmarshaler{}).UnmarshalMetrics(r.payload)
+	case probe.LOG:
+		d.Logs, err = (&plog.ProtoUnmarshaler{}).UnmarshalLogs(r.payload)
+	case probe.TRACE:
+		d.Traces, err = (&ptrace.ProtoUnmarshaler{}).UnmarshalTraces(r.payload)
+	default:
+		err = fmt.Errorf("unknown kind of signal %v", r.kind)
+	}
+	return d, err
+}
+
+// recoverCapture appends signals received after since which match the filter
+// to the bucket, it returns the number of appended signals. Segments are read
+// from the newest one until there are limit signals (zero means no limit),
+// so only the ones kept by the bucket are decoded and checked by the linter
+// (nil disables it). Bad segments and records are logged and skipped.
+func recoverCapture(dir string, bucket probe.Bucket, filter *Query, linter *probe.Linter, since time.Time, limit int) (int, error) {
+	segments, err := captureSegments(dir)
+	if err != nil {
+		return 0, err
+	}
+	signals := make([]*probe.Signal, 0)
+	for i := len(segments) - 1; i >= 0 && (limit <= 0 || len(signals) < limit); i-- {
+		if !since.IsZero() {
+			fi, err := os.Stat(segments[i].path)
+			if err != nil {
+				log.Printf("capture: skipped segment: %v", err)
+				continue
+			}
+			if fi.ModTime().Before(since) {
+				// older segments contain only older records
+				break
+			}
+		}
+		found := make([]*probe.Signal, 0)
+		err := readSegment(segments[i].path, func(r CaptureRecord) error {
+			if r.received.Before(since) {
+				return nil
+			}
+			d, err := r.decode()
+			if err != nil {
+				log.Printf("capture %s: skipped record received at %v: %v", segments[i].path, r.received, err)
+				return nil
+			}
+			return d.Signals(func(s *probe.Signal) error {
+				if filter.match(s) {
+					found = append(found, s)
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			log.Printf("capture: skipped segment: %v", err)
+		}
+		signals = append(found, signals...)
+	}
+	if limit > 0 && len(signals) > limit {
+		signals = signals[len(signals)-limit:]
+	}
+	for _, s := range signals {
+		if linter != nil {
+			s.Lint = linter.Check(s)
+		}
+		bucket.Append(s)
+	}
+	return len(signals), nil
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tomplus/otlprobe/probe"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+)
+
+func newCaptureLogs(body string) plogotlp.ExportRequest {
+	ld := plog.NewLogs()
+	rl := ld.ResourceLogs().AppendEmpty()
+	rl.Resource().Attributes().PutStr("service.name", "checkout")
+	rl.ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr(body)
+	return plogotlp.NewExportRequestFromLogs(ld)
+}
+
+func TestCaptureRecover(t *testing.T) {
+
+	dir := t.TempDir()
+	c, err := newCapture(dir, 200, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, body := range []string{"one", "two", "three"} {
+		c.HandleLogs(newCaptureLogs(body))
+	}
+	td := ptrace.NewTraces()
+	td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("GET /")
+	c.HandleTraces(ptraceotlp.NewExportRequestFromTraces(td))
+	if err := c.close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-c.errors():
+		t.Fatal(err)
+	default:
+	}
+
+	segments, err := captureSegments(dir)
+	if err != nil || len(segments) < 2 {
+		t.Errorf("segments should be rotated => %v, %v", segments, err)
+	}
+
+	bucket := probe.NewBucketFixedSize(10)
+	n, err := recoverCapture(dir, bucket, &Query{}, nil, time.Time{}, 0)
+	if err != nil || n != 4 || bucket.Len() != 4 {
+		t.Fatalf("invalid recovery => %v, %v, %v", n, err, bucket.Len())
+	}
+	for i, expected := range []string{"[Unspecified], , GET /", ": three", ": two", ": one"} {
+		if _, s := bucket.Get(i); s.Summary()[:len(expected)] != expected {
+			t.Errorf("invalid signal %d => %v", i, s.Summary())
+		}
+	}
+	if _, s := bucket.Get(1); !containsProp(s.Properties()[2].Get(), "Attributes.service.name", "checkout") {
+		t.Errorf("invalid resource => %v", s.Properties()[2].Get())
+	}
+
+	// the next session continues with a new segment
+	c, err = newCapture(dir, 200, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.HandleLogs(newCaptureLogs("four"))
+	c.close()
+	bodies := []string{}
+	err = readCapture(dir, func(r CaptureRecord) error {
+		d, err := r.decode()
+		if err != nil {
+			return err
+		}
+		return d.Signals(func(s *probe.Signal) error {
+			if s.Kind == probe.LOG {
+				bodies = append(bodies, s.Record.Body().Str())
+			}
+			return nil
+		})
+	})
+	if err != nil || len(bodies) != 4 || bodies[3] != "four" {
+		t.Errorf("invalid records => %v, %v", bodies, err)
+	}
+
+	// only records received after since are recovered
+	bucket = probe.NewBucketFixedSize(10)
+	if n, err := recoverCapture(dir, bucket, &Query{}, nil, time.Now().Add(time.Hour), 0); n != 0 || err != nil {
+		t.Errorf("invalid recovery since => %v, %v", n, err)
+	}
+
+}
+
+func TestCaptureTornRecord(t *testing.T) {
+
+	dir := t.TempDir()
+	c, err := newCapture(dir, defaultCaptureSegmentSize, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.HandleLogs(newCaptureLogs("one"))
+	c.HandleLogs(newCaptureLogs("two"))
+	c.close()
+
+	segments, _ := captureSegments(dir)
+	fi, err := os.Stat(segments[0].path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the last record is written only partially
+	if err := os.Truncate(segments[0].path, fi.Size()-3); err != nil {
+		t.Fatal(err)
+	}
+	// an empty segment is created just before a crash
+	if err := os.WriteFile(filepath.Join(dir, "capture-00000002.otlp"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	bucket := probe.NewBucketFixedSize(10)
+	if n, err := recoverCapture(dir, bucket, &Query{}, nil, time.Time{}, 0); n != 1 || err != nil {
+		t.Errorf("invalid recovery of torn record => %v, %v", n, err)
+	}
+
+	// corrupted payload of the first record
+	data, _ := os.ReadFile(segments[0].path)
+	data[captureHeaderSize+recordHeaderSize+2] ^= 0xff
+	os.WriteFile(segments[0].path, data, 0o644)
+	bucket = probe.NewBucketFixedSize(10)
+	if n, err := recoverCapture(dir, bucket, &Query{}, nil, time.Time{}, 0); n != 0 || err != nil {
+		t.Errorf("invalid recovery of corrupted record => %v, %v", n, err)
+	}
+
+	// a record which can't be decoded and an invalid segment are skipped
+	c, _ = newCapture(dir, defaultCaptureSegmentSize, 0)
+	c.write(probe.LOG, time.Now(), []byte("not a request"))
+	c.HandleLogs(newCaptureLogs("three"))
+	c.close()
+	os.WriteFile(segments[0].path, []byte("not a capture"), 0o644)
+	bucket = probe.NewBucketFixedSize(10)
+	if n, err := recoverCapture(dir, bucket, &Query{}, nil, time.Time{}, 0); n != 1 || err != nil {
+		t.Errorf("bad records and segments should be skipped => %v, %v", n, err)
+	}
+
+}
+
+func TestCaptureRecoverSince(t *testing.T) {
+
+	dir := t.TempDir()
+	for _, body := range []string{"old", "new"} {
+		c, err := newCapture(dir, defaultCaptureSegmentSize, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.HandleLogs(newCaptureLogs(body))
+		td := ptrace.NewTraces()
+		td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("GET /")
+		c.HandleTraces(ptraceotlp.NewExportRequestFromTraces(td))
+		c.close()
+	}
+
+	// the old segment isn't read at all
+	segments, _ := captureSegments(dir)
+	os.WriteFile(segments[0].path, []byte("not a capture"), 0o644)
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(segments[0].path, old, old)
+
+	linter, _ := probe.NewLinter("")
+	bucket := probe.NewBucketFixedSize(10)
+	n, err := recoverCapture(dir, bucket, &Query{}, linter, time.Now().Add(-time.Hour), 0)
+	if n != 2 || err != nil {
+		t.Fatalf("invalid recovery => %v, %v", n, err)
+	}
+	// recovered signals are linted like received ones
+	if _, s := bucket.Get(0); len(s.Lint) == 0 || s.Lint[0].Rule != probe.LintMissing {
+		t.Errorf("recovered span should be linted => %v", s.Lint)
+	}
+	if _, s := bucket.Get(1); s.Record.Body().Str() != "new" || len(s.Lint) != 0 {
+		t.Errorf("invalid recovered log => %v, %v", s.Summary(), s.Lint)
+	}
+
+}
+
+func TestCaptureMaxSize(t *testing.T) {
+
+	dir := t.TempDir()
+	c, err := newCapture(dir, 100, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		c.HandleLogs(newCaptureLogs("message"))
+	}
+	c.close()
+
+	segments, _ := captureSegments(dir)
+	total := int64(0)
+	for _, s := range segments {
+		fi, _ := os.Stat(s.path)
+		total += fi.Size()
+	}
+	if total > 300+100 || segments[0].seq == 1 || segments[len(segments)-1].seq < 10 {
+		t.Errorf("old segments should be removed => %v, %d bytes", segments, total)
+	}
+
+}
+
+func TestCaptureServer(t *testing.T) {
+
+	dir := t.TempDir()
+	ch := make(chan *probe.Signal, 10)
+	server := probe.NewServer(0, 0, ch)
+	capture, _ := newCapture(dir, defaultCaptureSegmentSize, 0)
+	server.Handlers = append(server.Handlers, capture)
+	body, _ := newCaptureLogs("one").MarshalProto()
+	req := httptest.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader(body))
+	req.Header.Set("Content-Type", probe.ContentTypeProto)
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("invalid status => %d %s", rec.Code, rec.Body.String())
+	}
+	capture.close()
+
+	records := 0
+	readCapture(dir, func(r CaptureRecord) error {
+		if r.kind != probe.LOG || time.Since(r.received) > time.Minute {
+			t.Errorf("invalid record => %v, %v", r.kind, r.received)
+		}
+		records++
+		return nil
+	})
+	if records != 1 || len(ch) != 1 {
+		t.Errorf("invalid number of records => %d, %d", records, len(ch))
+	}
+
+}
+
+func containsProp(props [][]string, name string, value string) bool {
+	for _, p := range props {
+		if p[0] == name && p[1] == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCaptureRecoverNewest(t *testing.T) {
+
+	dir := t.TempDir()
+	for _, bodies := range [][]string{{"one", "two"}, {"three", "four"}} {
+		c, err := newCapture(dir, defaultCaptureSegmentSize, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, body := range bodies {
+			c.HandleLogs(newCaptureLogs(body))
+		}
+		c.close()
+	}
+
+	// segments are read from the newest one until the bucket is full
+	for limit, expected := range map[int][]string{2: {"four", "three"}, 3: {"four", "three", "two"}, 0: {"four", "three", "two", "one"}} {
+		bucket := probe.NewBucketFixedSize(10)
+		n, err := recoverCapture(dir, bucket, &Query{}, nil, time.Time{}, limit)
+		if n != len(expected) || err != nil {
+			t.Errorf("invalid recovery with limit %d => %v, %v", limit, n, err)
+			continue
+		}
+		for i, body := range expected {
+			if _, s := bucket.Get(i); s.Record.Body().Str() != body {
+				t.Errorf("invalid signal %d with limit %d => %v", i, limit, s.Record.Body().Str())
+			}
+		}
+	}
+
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/tomplus/otlprobe/probe"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// Chart draws values of a single time series (metric name, attributes and resource)
+// found in the bucket as a line chart. It's refreshed with the browser,
+// so new data points show up as they arrive.
+type Chart struct {
+	screen      tcell.Screen
+	bucket      probe.Bucket
+	visible     bool
+	key         string
+	monotonic   bool
+	rate        bool
+	textStyle   tcell.Style
+	headerStyle tcell.Style
+	axisStyle   tcell.Style
+	lineStyle   tcell.Style
+}
+
+// chartPoint is a single value of the series.
+type chartPoint struct {
+	time  pcommon.Timestamp
+	start pcommon.Timestamp
+	value float64
+}
+
+// brailleCanvas is a canvas with 2x4 dots per terminal cell.
+type brailleCanvas struct {
+	width  int
+	height int
+	cells  []uint8
+}
+
+var brailleDots = [4][2]uint8{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+
+func newChart(screen tcell.Screen) *Chart {
+	c := Chart{
+		screen:      screen,
+		textStyle:   tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack),
+		headerStyle: tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorLightCyan),
+		axisStyle:   tcell.StyleDefault.Foreground(tcell.ColorDarkGray).Background(tcell.ColorBlack),
+		lineStyle:   tcell.StyleDefault.Foreground(tcell.ColorLightGreen).Background(tcell.ColorBlack),
+	}
+	return &c
+}
+
+// chartable returns true for signals with a number data point (gauges and sums).
+func chartable(s *probe.Signal) bool {
+	_, ok := probe.NumberDataPoint(s)
+	return ok
+}
+
+func isMonotonicCumulative(m pmetric.Metric) bool {
+	return m.Type() == pmetric.MetricTypeSum && m.Sum().IsMonotonic() &&
+		m.Sum().AggregationTemporality() == pmetric.AggregationTemporalityCumulative
+}
+
+// seriesKey identifies the time series of the resource, e.g.
+// http.requests{method=GET,status=200} @ cart/1, so the same metric of
+// different instances isn't merged.
+func seriesKey(s *probe.Signal) string {
+	dp, _ := probe.NumberDataPoint(s)
+	key := s.Metric.Name() + "{" + probe.AttributesString(dp.Attributes()) + "}"
+	if resource := resourceKey(s.Resource); resource != "" {
+		key += " @ " + resource
+	}
+	return key
+}
+
+// resourceKey returns service.name/service.instance.id of the resource, or
+// a hash of its attributes if both are missing.
+func resourceKey(resource pcommon.Resource) string {
+	attrs := resource.Attributes()
+	ids := make([]string, 0, 2)
+	for _, name := range []string{"service.name", "service.instance.id"} {
+		if v, ok := attrs.Get(name); ok && v.AsString() != "" {
+			ids = append(ids, v.AsString())
+		}
+	}
+	if len(ids) > 0 {
+		return strings.Join(ids, "/")
+	}
+	if attrs.Len() == 0 {
+		return ""
+	}
+	h := fnv.New32a()
+	h.Write([]byte(probe.AttributesString(attrs)))
+	return fmt.Sprintf("#%08x", h.Sum32())
+}
+
+func numberValue(dp pmetric.NumberDataPoint) (float64, bool) {
+	if dp.Flags().NoRecordedValue() {
+		return 0, false
+	}
+	switch dp.ValueType() {
+	case pmetric.NumberDataPointValueTypeInt:
+		return float64(dp.IntValue()), true
+	case pmetric.NumberDataPointValueTypeDouble:
+		return dp.DoubleValue(), !math.IsNaN(dp.DoubleValue())
+	}
+	return 0, false
+}
+
+// seriesPoints collects data points of the series from the bucket, oldest first.
+func seriesPoints(bucket probe.Bucket, key string) []chartPoint {
+	points := make([]chartPoint, 0)
+	for i := bucket.Len() - 1; i >= 0; i-- {
+		ok, s := bucket.Get(i)
+		if !ok || !chartable(s) || seriesKey(s) != key {
+			continue
+		}
+		if p, ok := newChartPoint(s); ok {
+			points = append(points, p)
+		}
+	}
+	sortChartPoints(points)
+	return points
+}
+
+func newChartPoint(s *probe.Signal) (chartPoint, bool) {
+	dp, _ := probe.NumberDataPoint(s)
+	v, ok := numberValue(dp)
+	return chartPoint{time: dp.Timestamp(), start: dp.StartTimestamp(), value: v}, ok
+}
+
+func sortChartPoints(points []chartPoint) {
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].time < points[j].time
+	})
+}
+
+// ratePoints converts cumulative values to per-second rate. A drop of the value
+// or a new start timestamp is treated as counter reset, i.e. the counter
+// restarted from zero.
+func ratePoints(points []chartPoint) []chartPoint {
+	res := make([]chartPoint, 0, len(points))
+	for i := 1; i < len(points); i++ {
+		prev, cur := points[i-1], points[i]
+		elapsed := cur.time - prev.time
+		delta := cur.value - prev.value
+		if cur.value < prev.value || (cur.start != 0 && cur.start != prev.start) {
+			delta = cur.value
+			if cur.start != 0 && cur.start > prev.time && cur.start < cur.time {
+				elapsed = cur.time - cur.start
+			}
+		}
+		if cur.time <= prev.time || elapsed == 0 {
+			continue
+		}
+		res = append(res, chartPoint{time: cur.time, start: cur.start, value: delta / time.Duration(elapsed).Seconds()})
+	}
+	return res
+}
+
+func newBrailleCanvas(width int, height int) *brailleCanvas {
+	return &brailleCanvas{width: width, height: height, cells: make([]uint8, width*height)}
+}
+
+// set turns on the dot, coordinates are in dots and (0, 0) is the top left corner.
+func (c *brailleCanvas) set(x int, y int) {
+	if x < 0 || y < 0 || x >= c.width*2 || y >= c.height*4 {
+		return
+	}
+	c.cells[(y/4)*c.width+x/2] |= brailleDots[y%4][x%2]
+}
+
+// line draws the line using Bresenham's algorithm.
+func (c *brailleCanvas) line(x0 int, y0 int, x1 int, y1 int) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		c.set(x0, y0)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		if 2*e >= dy {
+			e += dy
+			x0 += sx
+		}
+		if 2*e <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func (c *brailleCanvas) rune(col int, row int) rune {
+	return rune(0x2800 + int(c.cells[row*c.width+col]))
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// valueRange returns min and max value, flat series get a margin so they're
+// drawn in the middle.
+func valueRange(points []chartPoint) (float64, float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, p := range points {
+		lo = min(lo, p.value)
+		hi = max(hi, p.value)
+	}
+	if lo == hi {
+		return lo - 1, hi + 1
+	}
+	return lo, hi
+}
+
+// plot draws the points on the canvas of given size (in cells).
+func plot(points []chartPoint, width int, height int) *brailleCanvas {
+	c := newBrailleCanvas(width, height)
+	if len(points) == 0 || width <= 0 || height <= 0 {
+		return c
+	}
+	lo, hi := valueRange(points)
+	t0, t1 := points[0].time, points[len(points)-1].time
+	xy := func(p chartPoint) (int, int) {
+		x := 0
+		if t1 > t0 {
+			x = int(math.Round(float64(p.time-t0) / float64(t1-t0) * float64(width*2-1)))
+		}
+		y := int(math.Round((hi - p.value) / (hi - lo) * float64(height*4-1)))
+		return x, y
+	}
+	px, py := xy(points[0])
+	c.set(px, py)
+	for _, p := range points[1:] {
+		x, y := xy(p)
+		c.line(px, py, x, y)
+		px, py = x, y
+	}
+	return c
+}
+
+func (chart *Chart) show(bucket probe.Bucket, selected *probe.Signal) {
+	chart.bucket = bucket
+	chart.key = seriesKey(selected)
+	chart.monotonic = isMonotonicCumulative(selected.Metric)
+	chart.rate = chart.monotonic
+	chart.visible = true
+}
+
+func (chart *Chart) drawText(x int, y int, width int, style tcell.Style, text string) {
+	runes := []rune(text)
+	for col := 0; col < width; col++ {
+		r := ' '
+		if col < len(runes) {
+			r = runes[col]
+		}
+		chart.screen.SetContent(x+col, y, r, nil, style)
+	}
+}
+
+func (chart *Chart) refresh() {
+
+	if !chart.visible {
+		return
+	}
+
+	w, h := chart.screen.Size()
+	points := seriesPoints(chart.bucket, chart.key)
+	title := " " + chart.key
+	if chart.rate {
+		points = ratePoints(points)
+		title += "  rate/s"
+	}
+	chart.drawText(0, 0, w, chart.headerStyle, title)
+
+	readouts := " no data points"
+	if len(points) > 0 {
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for _, p := range points {
+			lo = min(lo, p.value)
+			hi = max(hi, p.value)
+		}
+		readouts = fmt.Sprintf(" min=%.4g  max=%.4g  last=%.4g  points=%d", lo, hi, points[len(points)-1].value, len(points))
+	}
+	chart.drawText(0, 1, w, chart.textStyle, readouts)
+
+	// y-axis labels on the left, x-axis with time in the last row
+	top, bottom := 2, h-3
+	height := bottom - top + 1
+	lo, hi := 0.0, 0.0
+	if len(points) > 0 {
+		lo, hi = valueRange(points)
+	}
+	labels := []string{fmt.Sprintf("%.4g", hi), fmt.Sprintf("%.4g", (lo+hi)/2), fmt.Sprintf("%.4g", lo)}
+	labelWidth := 0
+	for _, l := range labels {
+		labelWidth = max(labelWidth, len(l))
+	}
+	plotX := labelWidth + 2
+	width := w - plotX
+	if height <= 0 || width <= 0 {
+		return
+	}
+
+	canvas := plot(points, width, height)
+	for row := 0; row < height; row++ {
+		label := ""
+		switch row {
+		case 0:
+			label = labels[0]
+		case height / 2:
+			label = labels[1]
+		case height - 1:
+			label = labels[2]
+		}
+		chart.drawText(0, top+row, labelWidth+1, chart.axisStyle, fmt.Sprintf("%*s ", labelWidth, label))
+		chart.screen.SetContent(labelWidth+1, top+row, '┤', nil, chart.axisStyle)
+		for col := 0; col < width; col++ {
+			chart.screen.SetContent(plotX+col, top+row, canvas.rune(col, row), nil, chart.lineStyle)
+		}
+	}
+
+	axis := strings.Repeat(" ", labelWidth+1) + "└" + strings.Repeat("─", width)
+	chart.drawText(0, h-2, w, chart.axisStyle, axis)
+	if len(points) > 0 {
+		first := points[0].time.AsTime().Local().Format(time.TimeOnly)
+		last := points[len(points)-1].time.AsTime().Local().Format(time.TimeOnly)
+		chart.drawText(plotX+1, h-2, len(first), chart.axisStyle, first)
+		if width > 2*len(last)+4 {
+			chart.drawText(w-len(last)-1, h-2, len(last), chart.axisStyle, last)
+		}
+	}
+
+}
+
+func (chart *Chart) eventKey(ev *tcell.EventKey) bool {
+
+	if ev.Key() == tcell.KeyEscape {
+		chart.visible = false
+		return true
+	}
+	if ev.Rune() == 'R' && chart.monotonic {
+		chart.rate = !chart.rate
+		return true
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tomplus/otlprobe/probe"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestSeriesPoints(t *testing.T) {
+
+	md := pmetric.NewMetrics()
+	ms := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+	m := ms.AppendEmpty()
+	m.SetName("requests")
+	sum := m.SetEmptySum()
+	sum.SetIsMonotonic(true)
+	sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	for i, v := range []int64{10, 20, 5, 7} {
+		for _, method := range []string{"GET", "POST"} {
+			dp := sum.DataPoints().AppendEmpty()
+			dp.Attributes().PutStr("status", "200")
+			dp.Attributes().PutStr("method", method)
+			dp.SetTimestamp(pcommon.Timestamp(time.Duration(i+1) * time.Second))
+			dp.SetIntValue(v)
+		}
+	}
+	ms.AppendEmpty().SetEmptyHistogram().DataPoints().AppendEmpty()
+
+	ch := make(chan *probe.Signal, 20)
+	server := probe.NewServer(0, 0, ch)
+	if err := server.Process(context.Background(), probe.OTLPData{Kind: probe.METRIC, Metrics: md}); err != nil {
+		t.Fatal(err)
+	}
+	close(ch)
+	b := probe.NewBucketFixedSize(20)
+	for s := range ch {
+		b.Append(s)
+	}
+
+	ok, selected := b.Get(b.Len() - 1)
+	if !ok || !chartable(selected) || !isMonotonicCumulative(selected.Metric) {
+		t.Fatalf("invalid selected signal => %v", selected)
+	}
+	if key := seriesKey(selected); key != "requests{method=GET,status=200}" {
+		t.Errorf("invalid seriesKey() => %v", key)
+	}
+	if _, hist := b.Get(0); chartable(hist) {
+		t.Errorf("histogram should not be chartable")
+	}
+
+	points := seriesPoints(b, seriesKey(selected))
+	if len(points) != 4 || points[0].value != 10 || points[3].value != 7 {
+		t.Errorf("invalid seriesPoints() => %v", points)
+	}
+
+	// 10 -> 20 -> reset to 5 -> 7
+	rates := ratePoints(points)
+	if len(rates) != 3 || rates[0].value != 10 || rates[1].value != 5 || rates[2].value != 2 {
+		t.Errorf("invalid ratePoints() => %v", rates)
+	}
+
+}
+
+func TestSeriesKeyResource(t *testing.T) {
+
+	md := pmetric.NewMetrics()
+	for _, instance := range []string{"1", "2", ""} {
+		rm := md.ResourceMetrics().AppendEmpty()
+		if instance != "" {
+			rm.Resource().Attributes().PutStr("service.name", "cart")
+			rm.Resource().Attributes().PutStr("service.instance.id", instance)
+		} else {
+			rm.Resource().Attributes().PutStr("host.name", "node-1")
+		}
+		rm.ScopeMetrics().AppendEmpty().Metrics().AppendEmpty().SetEmptyGauge().DataPoints().AppendEmpty().SetIntValue(1)
+	}
+	keys := make([]string, 0)
+	probe.MetricSignals(md, func(s *probe.Signal) error {
+		keys = append(keys, seriesKey(s))
+		return nil
+	})
+	if len(keys) != 3 || keys[0] != "{} @ cart/1" || keys[1] != "{} @ cart/2" || !strings.HasPrefix(keys[2], "{} @ #") {
+		t.Errorf("series of instances should be distinct => %q", keys)
+	}
+
+}
+
+func TestRatePointsStartTimestamp(t *testing.T) {
+
+	s := pcommon.Timestamp(time.Second)
+	points := []chartPoint{
+		{time: 10 * s, start: 1 * s, value: 100},
+		{time: 20 * s, start: 1 * s, value: 200},
+		{time: 30 * s, start: 25 * s, value: 500},
+	}
+	rates := ratePoints(points)
+	if len(rates) != 2 || rates[0].value != 10 || rates[1].value != 100 {
+		t.Errorf("invalid ratePoints() => %v", rates)
+	}
+
+}
+
+func TestPlot(t *testing.T) {
+
+	points := []chartPoint{{time: 0, value: 0}, {time: 1, value: 1}}
+	c := plot(points, 2, 1)
+	// diagonal from the bottom left to the top right corner
+	if r := string([]rune{c.rune(0, 0), c.rune(1, 0)}); r != "⡠⠊" {
+		t.Errorf("invalid plot => %q", r)
+	}
+	if c := plot(nil, 2, 1); c.rune(0, 0) != '⠀' {
+		t.Errorf("empty plot should be blank")
+	}
+
+}
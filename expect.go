@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tomplus/otlprobe/probe"
+)
+
+// maxExpectExamples is the number of matched signals shown in the report.
+const maxExpectExamples = 3
+
+var expectCountRe = regexp.MustCompile(`^(>=|<=|>|<|=)(\d+)\s+`)
+
+// Expectation is a query with the expected number of matching signals.
+type Expectation struct {
+	query    *Query
+	op       string
+	count    int
+	never    bool
+	matched  int
+	examples []string
+}
+
+// parseExpectation parses a query optionally prefixed with the expected
+// count, e.g. '>=3 kind:log', by default at least one signal is expected.
+func parseExpectation(spec string, never bool) (*Expectation, error) {
+	e := Expectation{op: ">=", count: 1, never: never}
+	if never {
+		e.op, e.count = "=", 0
+	} else if m := expectCountRe.FindStringSubmatch(spec); m != nil {
+		e.op = m[1]
+		e.count, _ = strconv.Atoi(m[2])
+		spec = spec[len(m[0]):]
+	}
+	if strings.TrimSpace(spec) == "" {
+		return nil, fmt.Errorf("empty expectation")
+	}
+	q, err := parseQuery(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expectation %q: %w", spec, err)
+	}
+	e.query = q
+	return &e, nil
+}
+
+func (e *Expectation) String() string {
+	if e.never {
+		return "never " + e.query.String()
+	}
+	return fmt.Sprintf("%s%d %s", e.op, e.count, e.query.String())
+}
+
+func (e *Expectation) observe(i int, s *probe.Signal) {
+	if !e.query.match(s) {
+		return
+	}
+	e.matched++
+	if len(e.examples) < maxExpectExamples {
+		e.examples = append(e.examples, fmt.Sprintf("%v: %v %v", i, s.Time.AsTime().String()[0:23], s.Summary()))
+	}
+}
+
+func (e *Expectation) met() bool {
+	switch e.op {
+	case ">":
+		return e.matched > e.count
+	case "<=":
+		return e.matched <= e.count
+	case "<":
+		return e.matched < e.count
+	case "=":
+		return e.matched == e.count
+	}
+	return e.matched >= e.count
+}
+
+// violated reports expectations which can't be met anymore, e.g. never
+// after the first match.
+func (e *Expectation) violated() bool {
+	switch e.op {
+	case "<=", "=":
+		return e.matched > e.count
+	case "<":
+		return e.matched >= e.count
+	}
+	return false
+}
+
+// waiting reports expectations which need signals to be met.
+func (e *Expectation) waiting() bool {
+	return e.op == ">=" || e.op == ">" || (e.op == "=" && e.count > 0)
+}
+
+// Expect checks received signals against the expectations.
+type Expect struct {
+	expectations []*Expectation
+	signals      int
+}
+
+func newExpect(expectations []*Expectation) *Expect {
+	x := Expect{expectations: expectations}
+	return &x
+}
+
+func (x *Expect) observe(s *probe.Signal) {
+	x.signals++
+	for _, e := range x.expectations {
+		e.observe(x.signals, s)
+	}
+}
+
+func (x *Expect) met() bool {
+	for _, e := range x.expectations {
+		if !e.met() {
+			return false
+		}
+	}
+	return true
+}
+
+func (x *Expect) violated() bool {
+	for _, e := range x.expectations {
+		if e.violated() {
+			return true
+		}
+	}
+	return false
+}
+
+func (x *Expect) waiting() bool {
+	for _, e := range x.expectations {
+		if e.waiting() {
+			return true
+		}
+	}
+	return false
+}
+
+// wait consumes signals until all expectations are met, one of them is
+// violated or the timeout expires. After the expected signals arrived it
+// keeps checking the rest (e.g. never) for the settle period. Without
+// expectations waiting for signals it runs until the timeout.
+func (x *Expect) wait(ctx context.Context, ch <-chan *probe.Signal, timeout time.Duration, settle time.Duration) bool {
+	deadline := time.After(timeout)
+	var settled <-chan time.Time
+	for {
+		if x.violated() {
+			return false
+		}
+		if settled == nil && x.waiting() && x.met() {
+			if settle <= 0 {
+				return true
+			}
+			settled = time.After(settle)
+		}
+		select {
+		case s := <-ch:
+			x.observe(s)
+		case <-settled:
+			return x.met()
+		case <-deadline:
+			return x.met()
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// report prints the result of every expectation with a few matched signals.
+func (x *Expect) report(w io.Writer, elapsed time.Duration, color bool) {
+	mark := func(ok bool) string {
+		text, c := "FAIL", ansiRed
+		if ok {
+			text, c = "PASS", ansiGreen
+		}
+		if !color {
+			return text
+		}
+		return c + text + ansiReset
+	}
+	met := 0
+	for _, e := range x.expectations {
+		if e.met() {
+			met++
+		}
+		expected := fmt.Sprintf(", expected %s%d", e.op, e.count)
+		if e.never {
+			expected = ""
+		}
+		fmt.Fprintf(w, "%s  %v: %d matched%s\n", mark(e.met()), e, e.matched, expected)
+		for _, example := range e.examples {
+			fmt.Fprintf(w, "        %s\n", example)
+		}
+		if more := e.matched - len(e.examples); more > 0 {
+			fmt.Fprintf(w, "        ... and %d more\n", more)
+		}
+	}
+	fmt.Fprintf(w, "%s  %d of %d expectations met, %d signals received in %v\n",
+		mark(met == len(x.expectations)), met, len(x.expectations), x.signals, elapsed.Round(time.Millisecond))
+}
+
+// runExpect receives signals until the expectations are met and exits
+// with 0, otherwise with 1 (e.g. in integration tests).
+// serveExpect listens on the enabled ports first, so a port in use is
+// reported as a failure of the check, and serves requests in the background.
+func serveExpect(server *probe.Server, grpcPort int, httpPort int) error {
+	listeners := make([]net.Listener, 0, 2)
+	for _, port := range []int{grpcPort, httpPort} {
+		if port <= 0 {
+			listeners = append(listeners, nil)
+			continue
+		}
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+		if err != nil {
+			for _, l := range listeners {
+				if l != nil {
+					l.Close()
+				}
+			}
+			return fmt.Errorf("failed to listen: %w", err)
+		}
+		listeners = append(listeners, lis)
+	}
+	serve := func(name string, fn func(lis net.Listener) error, lis net.Listener) {
+		if lis == nil {
+			return
+		}
+		go func() {
+			if err := fn(lis); err != nil {
+				log.Printf("%s receiver stopped: %v", name, err)
+			}
+		}()
+	}
+	serve("gRPC", server.ServeGRPC, listeners[0])
+	serve("HTTP", server.ServeOTLPHTTP, listeners[1])
+	return nil
+}
+
+func runExpect(args []string) {
+
+	fs := flag.NewFlagSet("expect", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: otlprobe expect --match <query> [--never <query>] [options]\n\n")
+		fs.PrintDefaults()
+	}
+	grpcPortPtr := fs.Int("grpc-port", 4317, "port for gRPC server, 0 disables it")
+	httpPortPtr := fs.Int("http-port", 4318, "port for HTTP server, 0 disables it")
+	expectations := make([]*Expectation, 0)
+	fs.Func("match", "expected signals, optionally with the count, e.g. 'name=\"POST /orders\" attr.http.status_code=201' or '>=3 kind:log' (repeatable)", func(spec string) error {
+		e, err := parseExpectation(spec, false)
+		if err == nil {
+			expectations = append(expectations, e)
+		}
+		return err
+	})
+	fs.Func("never", "signals which must not be received, e.g. 'severity>=ERROR' (repeatable)", func(spec string) error {
+		e, err := parseExpectation(spec, true)
+		if err == nil {
+			expectations = append(expectations, e)
+		}
+		return err
+	})
+	timeoutPtr := fs.Duration("timeout", 30*time.Second, "time to wait for the expected signals")
+	settlePtr := fs.Duration("settle", 0, "keep checking --never and upper bounds for the period after the expected signals arrived")
+	colorPtr := fs.String("color", "auto", "colorize the report: auto (when stdout is a terminal), always or never")
+	fs.Parse(args)
+	if len(expectations) == 0 || fs.NArg() > 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *grpcPortPtr <= 0 && *httpPortPtr <= 0 {
+		log.Fatalln("Disabled gRPC and HTTP")
+	}
+	color, err := useColor(*colorPtr, os.Stdout)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	ch := make(chan *probe.Signal)
+	server := probe.NewServer(max(*grpcPortPtr, 0), max(*httpPortPtr, 0), ch)
+	if err := serveExpect(server, *grpcPortPtr, *httpPortPtr); err != nil {
+		fmt.Fprintf(os.Stderr, "expect: %v\n", err)
+		os.Exit(1)
+	}
+	defer server.Stop()
+	fmt.Fprintf(os.Stderr, "waiting up to %v for %d expectations (gRPC port %d, HTTP port %d)\n",
+		*timeoutPtr, len(expectations), max(*grpcPortPtr, 0), max(*httpPortPtr, 0))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	x := newExpect(expectations)
+	start := time.Now()
+	ok := x.wait(ctx, ch, *timeoutPtr, *settlePtr)
+	x.report(os.Stdout, time.Since(start), color)
+	if !ok {
+		os.Exit(1)
+	}
+}
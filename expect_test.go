@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tomplus/otlprobe/probe"
+)
+
+func newTestExpect(t *testing.T, matches []string, nevers []string) *Expect {
+	expectations := make([]*Expectation, 0)
+	for _, spec := range matches {
+		e, err := parseExpectation(spec, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectations = append(expectations, e)
+	}
+	for _, spec := range nevers {
+		e, err := parseExpectation(spec, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectations = append(expectations, e)
+	}
+	return newExpect(expectations)
+}
+
+// sendSignals passes signals to the channel until the test is finished.
+func sendSignals(t *testing.T, signals []*probe.Signal) chan *probe.Signal {
+	ch := make(chan *probe.Signal)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() {
+		for _, s := range signals {
+			select {
+			case ch <- s:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+func TestParseExpectation(t *testing.T) {
+
+	for spec, expected := range map[string]string{
+		"kind:log":            ">=1 kind:log",
+		">=3 kind:log":        ">=3 kind:log",
+		"=0   name=x":         "=0 name=x",
+		"<2 attr.code=201":    "<2 attr.code=201",
+		`>1 name="POST /a" b`: `>1 name="POST /a" b`,
+	} {
+		if e, err := parseExpectation(spec, false); err != nil || e.String() != expected {
+			t.Errorf("invalid expectation %q => %v, %v", spec, e, err)
+		}
+	}
+	if e, err := parseExpectation("severity>=ERROR", true); err != nil || e.String() != "never severity>=ERROR" {
+		t.Errorf("invalid never expectation => %v, %v", e, err)
+	}
+	for _, spec := range []string{"", ">=3 ", "(name=x", ">=x kind:log"} {
+		if _, err := parseExpectation(spec, false); err == nil {
+			t.Errorf("expectation %q should fail", spec)
+		}
+	}
+
+}
+
+func TestExpectMet(t *testing.T) {
+
+	x := newTestExpect(t, []string{"kind:log severity>=ERROR", "=2 name=requests"}, []string{"kind:trace"})
+	ch := sendSignals(t, newOutputSignals())
+	start := time.Now()
+	if !x.wait(context.Background(), ch, 5*time.Second, 0) {
+		t.Errorf("expectations should be met")
+	}
+	if time.Since(start) > time.Second || x.signals != 3 {
+		t.Errorf("it should finish once the expectations are met => %v, %d", time.Since(start), x.signals)
+	}
+
+	var buf bytes.Buffer
+	x.report(&buf, time.Second, false)
+	lines := strings.Split(buf.String(), "\n")
+	if lines[0] != "PASS  >=1 kind:log severity>=ERROR: 1 matched, expected >=1" ||
+		!strings.HasSuffix(lines[1], "ERROR: payment failed") ||
+		lines[len(lines)-2] != "PASS  3 of 3 expectations met, 3 signals received in 1s" {
+		t.Errorf("invalid report => %v", buf.String())
+	}
+
+}
+
+func TestExpectFailed(t *testing.T) {
+
+	// never fails on the first match
+	x := newTestExpect(t, []string{"kind:trace"}, []string{"kind:metric"})
+	if x.wait(context.Background(), sendSignals(t, newOutputSignals()), 5*time.Second, 0) || x.signals != 2 {
+		t.Errorf("never should fail on the first match => %d", x.signals)
+	}
+
+	// missing signals fail on the timeout
+	x = newTestExpect(t, []string{">=3 kind:metric"}, nil)
+	start := time.Now()
+	if x.wait(context.Background(), sendSignals(t, newOutputSignals()), 100*time.Millisecond, 0) {
+		t.Errorf("expectation should not be met")
+	}
+	if time.Since(start) < 100*time.Millisecond {
+		t.Errorf("it should wait for the timeout")
+	}
+	var buf bytes.Buffer
+	x.report(&buf, time.Second, false)
+	if !strings.HasPrefix(buf.String(), "FAIL  >=3 kind:metric: 2 matched, expected >=3\n") {
+		t.Errorf("invalid report => %v", buf.String())
+	}
+
+	// settle keeps checking never after the expected signals
+	x = newTestExpect(t, []string{"kind:log"}, []string{"kind:metric"})
+	if x.wait(context.Background(), sendSignals(t, newOutputSignals()), 5*time.Second, time.Second) {
+		t.Errorf("never should be checked during the settle period")
+	}
+
+}
+
+func TestServeExpectPortInUse(t *testing.T) {
+
+	lis, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	port := lis.Addr().(*net.TCPAddr).Port
+
+	server := probe.NewServer(0, port, make(chan *probe.Signal))
+	defer server.Stop()
+	if err := serveExpect(server, 0, port); err == nil || !strings.Contains(err.Error(), "failed to listen") {
+		t.Errorf("port in use should fail => %v", err)
+	}
+
+}
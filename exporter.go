@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/tomplus/otlprobe/probe"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// Exporter sends OTLP export requests to an upstream endpoint.
+type Exporter interface {
+	exportMetrics(ctx context.Context, request pmetricotlp.ExportRequest) error
+	exportLogs(ctx context.Context, request plogotlp.ExportRequest) error
+	exportTraces(ctx context.Context, request ptraceotlp.ExportRequest) error
+	close() error
+}
+
+type ExporterOptions struct {
+	endpoint              string
+	headers               map[string]string
+	compression           string
+	tlsCAFile             string
+	tlsCertFile           string
+	tlsKeyFile            string
+	tlsInsecureSkipVerify bool
+}
+
+func newExporterOptions() *ExporterOptions {
+	return &ExporterOptions{headers: map[string]string{}}
+}
+
+// register adds flags of the options (except of the endpoint) with the
+// prefix, what describes the exported requests in the help.
+func (o *ExporterOptions) register(fs *flag.FlagSet, prefix string, what string) {
+	fs.Func(prefix+"header", "header (key=value) added to "+what+" (repeatable)", func(s string) error {
+		k, v, found := strings.Cut(s, "=")
+		if !found {
+			return fmt.Errorf("expected key=value, got %q", s)
+		}
+		o.headers[k] = v
+		return nil
+	})
+	fs.StringVar(&o.compression, prefix+"compression", "gzip", "compression of "+what+": none, gzip or zstd")
+	fs.StringVar(&o.tlsCAFile, prefix+"tls-ca", "", "CA file (PEM) to verify upstream certificate")
+	fs.StringVar(&o.tlsCertFile, prefix+"tls-cert", "", "client certificate file (PEM) for upstream mTLS")
+	fs.StringVar(&o.tlsKeyFile, prefix+"tls-key", "", "client private key file (PEM) for upstream mTLS")
+	fs.BoolVar(&o.tlsInsecureSkipVerify, prefix+"tls-insecure-skip-verify", false, "don't verify upstream certificate")
+}
+
+func (o ExporterOptions) tlsRequested() bool {
+	return o.tlsCAFile != "" || o.tlsCertFile != "" || o.tlsKeyFile != "" || o.tlsInsecureSkipVerify
+}
+
+// newExporter creates gRPC or HTTP exporter depending on the endpoint:
+// grpc://host:port, grpcs://host:port, http://host:port, https://host:port/path
+// or host:port (gRPC, secure if any TLS option is set).
+func newExporter(o ExporterOptions) (Exporter, error) {
+	switch o.compression {
+	case "", "none", "gzip", "zstd":
+	default:
+		return nil, fmt.Errorf("unsupported compression: %s", o.compression)
+	}
+
+	scheme, rest, found := strings.Cut(o.endpoint, "://")
+	if !found {
+		scheme, rest = "", o.endpoint
+	}
+	switch scheme {
+	case "":
+		return newGrpcExporter(rest, o, o.tlsRequested())
+	case "grpc":
+		return newGrpcExporter(rest, o, false)
+	case "grpcs":
+		return newGrpcExporter(rest, o, true)
+	case "http", "https":
+		return newHttpExporter(o)
+	}
+	return nil, fmt.Errorf("unsupported endpoint scheme: %s", scheme)
+}
+
+type grpcExporter struct {
+	conn        *grpc.ClientConn
+	metrics     pmetricotlp.GRPCClient
+	logs        plogotlp.GRPCClient
+	traces      ptraceotlp.GRPCClient
+	headers     metadata.MD
+	callOptions []grpc.CallOption
+}
+
+func newGrpcExporter(target string, o ExporterOptions, secure bool) (*grpcExporter, error) {
+	creds := insecure.NewCredentials()
+	if secure {
+		cfg, err := newClientTLSConfig(o.tlsCAFile, o.tlsCertFile, o.tlsKeyFile, o.tlsInsecureSkipVerify)
+		if err != nil {
+			return nil, err
+		}
+		creds = credentials.NewTLS(cfg)
+	}
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+	e := grpcExporter{
+		conn:    conn,
+		metrics: pmetricotlp.NewGRPCClient(conn),
+		logs:    plogotlp.NewGRPCClient(conn),
+		traces:  ptraceotlp.NewGRPCClient(conn),
+		headers: metadata.New(o.headers),
+	}
+	if o.compression != "" && o.compression != "none" {
+		e.callOptions = append(e.callOptions, grpc.UseCompressor(o.compression))
+	}
+	return &e, nil
+}
+
+func (e *grpcExporter) context(ctx context.Context) context.Context {
+	if len(e.headers) == 0 {
+		return ctx
+	}
+	return metadata.NewOutgoingContext(ctx, e.headers)
+}
+
+func (e *grpcExporter) exportMetrics(ctx context.Context, request pmetricotlp.ExportRequest) error {
+	resp, err := e.metrics.Export(e.context(ctx), request, e.callOptions...)
+	if err != nil {
+		return err
+	}
+	return partialSuccessError(resp.PartialSuccess().RejectedDataPoints(), "data points", resp.PartialSuccess().ErrorMessage())
+}
+
+func (e *grpcExporter) exportLogs(ctx context.Context, request plogotlp.ExportRequest) error {
+	resp, err := e.logs.Export(e.context(ctx), request, e.callOptions...)
+	if err != nil {
+		return err
+	}
+	return partialSuccessError(resp.PartialSuccess().RejectedLogRecords(), "log records", resp.PartialSuccess().ErrorMessage())
+}
+
+func (e *grpcExporter) exportTraces(ctx context.Context, request ptraceotlp.ExportRequest) error {
+	resp, err := e.traces.Export(e.context(ctx), request, e.callOptions...)
+	if err != nil {
+		return err
+	}
+	return partialSuccessError(resp.PartialSuccess().RejectedSpans(), "spans", resp.PartialSuccess().ErrorMessage())
+}
+
+func (e *grpcExporter) close() error {
+	return e.conn.Close()
+}
+
+type httpExporter struct {
+	client      *http.Client
+	baseURL     string
+	headers     map[string]string
+	compression string
+}
+
+func newHttpExporter(o ExporterOptions) (*httpExporter, error) {
+	u, err := url.Parse(o.endpoint)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if u.Scheme == "https" {
+		transport.TLSClientConfig, err = newClientTLSConfig(o.tlsCAFile, o.tlsCertFile, o.tlsKeyFile, o.tlsInsecureSkipVerify)
+		if err != nil {
+			return nil, err
+		}
+	}
+	e := httpExporter{
+		client:      &http.Client{Transport: transport},
+		baseURL:     strings.TrimSuffix(u.String(), "/"),
+		headers:     o.headers,
+		compression: o.compression,
+	}
+	return &e, nil
+}
+
+type otlpMarshaler interface {
+	MarshalProto() ([]byte, error)
+}
+
+type otlpUnmarshaler interface {
+	UnmarshalProto(data []byte) error
+}
+
+func (e *httpExporter) post(ctx context.Context, path string, preq otlpMarshaler, presp otlpUnmarshaler) error {
+	body, err := preq.MarshalProto()
+	if err != nil {
+		return err
+	}
+	body, err = compressBody(e.compression, body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", probe.ContentTypeProto)
+	if e.compression != "" && e.compression != "none" {
+		req.Header.Set("Content-Encoding", e.compression)
+	}
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		msg := strings.TrimSpace(string(data))
+		st := &spb.Status{}
+		if proto.Unmarshal(data, st) == nil && st.GetMessage() != "" {
+			msg = st.GetMessage()
+		}
+		return fmt.Errorf("upstream responded with %s: %s", resp.Status, msg)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == probe.ContentTypeProto {
+		return presp.UnmarshalProto(data)
+	}
+	return nil
+}
+
+func (e *httpExporter) exportMetrics(ctx context.Context, request pmetricotlp.ExportRequest) error {
+	resp := pmetricotlp.NewExportResponse()
+	if err := e.post(ctx, "/v1/metrics", request, resp); err != nil {
+		return err
+	}
+	return partialSuccessError(resp.PartialSuccess().RejectedDataPoints(), "data points", resp.PartialSuccess().ErrorMessage())
+}
+
+func (e *httpExporter) exportLogs(ctx context.Context, request plogotlp.ExportRequest) error {
+	resp := plogotlp.NewExportResponse()
+	if err := e.post(ctx, "/v1/logs", request, resp); err != nil {
+		return err
+	}
+	return partialSuccessError(resp.PartialSuccess().RejectedLogRecords(), "log records", resp.PartialSuccess().ErrorMessage())
+}
+
+func (e *httpExporter) exportTraces(ctx context.Context, request ptraceotlp.ExportRequest) error {
+	resp := ptraceotlp.NewExportResponse()
+	if err := e.post(ctx, "/v1/traces", request, resp); err != nil {
+		return err
+	}
+	return partialSuccessError(resp.PartialSuccess().RejectedSpans(), "spans", resp.PartialSuccess().ErrorMessage())
+}
+
+func (e *httpExporter) close() error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+func partialSuccessError(rejected int64, items string, msg string) error {
+	if rejected == 0 {
+		return nil
+	}
+	return fmt.Errorf("upstream rejected %d %s: %s", rejected, items, msg)
+}
+
+func compressBody(compression string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	var err error
+	switch compression {
+	case "", "none":
+		return data, nil
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "zstd":
+		w, err = zstd.NewWriter(&buf, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported compression: %s", compression)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/tomplus/otlprobe/probe"
+	"google.golang.org/grpc/codes"
+)
+
+// FaultPanel is a small dialog to change fault injection settings at runtime.
+type FaultPanel struct {
+	screen        tcell.Screen
+	faults        *probe.FaultInjector
+	visible       bool
+	row           int
+	col           int
+	frameStyle    tcell.Style
+	textStyle     tcell.Style
+	selectedStyle tcell.Style
+}
+
+var faultPanelSignals = []struct {
+	name string
+	kind probe.KindSignal
+}{
+	{"logs", probe.LOG},
+	{"metrics", probe.METRIC},
+	{"traces", probe.TRACE},
+}
+
+var faultPanelColumns = []struct {
+	name  string
+	width int
+}{
+	{"error rate", 12},
+	{"error code", 20},
+	{"retry after", 13},
+	{"latency", 9},
+	{"reject rate", 11},
+}
+
+var faultDurationSteps = []time.Duration{
+	0, 10 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond, 250 * time.Millisecond,
+	500 * time.Millisecond, time.Second, 2 * time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+}
+
+func newFaultPanel(screen tcell.Screen, faults *probe.FaultInjector) *FaultPanel {
+	p := FaultPanel{
+		screen:        screen,
+		faults:        faults,
+		frameStyle:    tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorDarkGray),
+		textStyle:     tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorDarkGray),
+		selectedStyle: tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite).Bold(true),
+	}
+	return &p
+}
+
+func (panel *FaultPanel) show() {
+	panel.visible = true
+	panel.refresh()
+}
+
+func (panel *FaultPanel) cell(cfg probe.FaultConfig, col int) string {
+	switch col {
+	case 0:
+		return fmt.Sprintf("%.0f%%", cfg.ErrorRate*100)
+	case 1:
+		return cfg.ErrorCode.String()
+	case 2:
+		return cfg.RetryAfter.String()
+	case 3:
+		return cfg.Latency.String()
+	case 4:
+		return fmt.Sprintf("%.0f%%", cfg.RejectRate*100)
+	}
+	return ""
+}
+
+func (panel *FaultPanel) refresh() {
+
+	if !panel.visible {
+		return
+	}
+
+	w, h := panel.screen.Size()
+	pw := 12
+	for _, c := range faultPanelColumns {
+		pw += c.width
+	}
+	ph := len(faultPanelSignals) + 5
+	x0, y0 := max((w-pw)/2, 0), max((h-ph)/2, 0)
+	x1, y1 := min(x0+pw, w-1), min(y0+ph, h-1)
+
+	for col := x0; col <= x1; col++ {
+		panel.screen.SetContent(col, y0, tcell.RuneHLine, nil, panel.frameStyle)
+		panel.screen.SetContent(col, y1, tcell.RuneHLine, nil, panel.frameStyle)
+	}
+	for row := y0 + 1; row < y1; row++ {
+		panel.screen.SetContent(x0, row, tcell.RuneVLine, nil, panel.frameStyle)
+		panel.screen.SetContent(x1, row, tcell.RuneVLine, nil, panel.frameStyle)
+		for col := x0 + 1; col < x1; col++ {
+			panel.screen.SetContent(col, row, ' ', nil, panel.textStyle)
+		}
+	}
+	panel.screen.SetContent(x0, y0, tcell.RuneULCorner, nil, panel.frameStyle)
+	panel.screen.SetContent(x1, y0, tcell.RuneURCorner, nil, panel.frameStyle)
+	panel.screen.SetContent(x0, y1, tcell.RuneLLCorner, nil, panel.frameStyle)
+	panel.screen.SetContent(x1, y1, tcell.RuneLRCorner, nil, panel.frameStyle)
+
+	panel.drawText(x0+2, y0+1, x1, panel.textStyle, "Fault injection  (↑↓←→ select, +/- change, Esc close)")
+	col := x0 + 11
+	for _, c := range faultPanelColumns {
+		panel.drawText(col, y0+3, x1, panel.textStyle, c.name)
+		col += c.width
+	}
+	for r, sig := range faultPanelSignals {
+		y := y0 + 4 + r
+		panel.drawText(x0+2, y, x1, panel.textStyle, sig.name)
+		cfg := panel.faults.Get(sig.kind)
+		col := x0 + 11
+		for c, column := range faultPanelColumns {
+			style := panel.textStyle
+			if r == panel.row && c == panel.col {
+				style = panel.selectedStyle
+			}
+			panel.drawText(col, y, x1, style, panel.cell(cfg, c))
+			col += column.width
+		}
+	}
+}
+
+func (panel *FaultPanel) drawText(x int, y int, limit int, style tcell.Style, text string) {
+	for _, r := range text {
+		if x >= limit {
+			return
+		}
+		panel.screen.SetContent(x, y, r, nil, style)
+		x++
+	}
+}
+
+func (panel *FaultPanel) adjust(delta int) {
+	kind := faultPanelSignals[panel.row].kind
+	cfg := panel.faults.Get(kind)
+	switch panel.col {
+	case 0:
+		cfg.ErrorRate = stepRate(cfg.ErrorRate, 0.05*float64(delta))
+	case 1:
+		if cfg.ErrorCode == codes.Unavailable {
+			cfg.ErrorCode = codes.ResourceExhausted
+		} else {
+			cfg.ErrorCode = codes.Unavailable
+		}
+	case 2:
+		cfg.RetryAfter = stepDuration(cfg.RetryAfter, delta)
+	case 3:
+		cfg.Latency = stepDuration(cfg.Latency, delta)
+	case 4:
+		cfg.RejectRate = stepRate(cfg.RejectRate, 0.1*float64(delta))
+	}
+	panel.faults.Set(kind, cfg)
+}
+
+func stepRate(v float64, delta float64) float64 {
+	v = math.Round((v+delta)*100) / 100
+	return math.Min(math.Max(v, 0), 1)
+}
+
+func stepDuration(v time.Duration, delta int) time.Duration {
+	i := 0
+	for i+1 < len(faultDurationSteps) && faultDurationSteps[i+1] <= v {
+		i++
+	}
+	i = min(max(i+delta, 0), len(faultDurationSteps)-1)
+	return faultDurationSteps[i]
+}
+
+func (panel *FaultPanel) eventKey(ev *tcell.EventKey) bool {
+
+	switch {
+	case ev.Key() == tcell.KeyEscape || ev.Rune() == 'X':
+		panel.visible = false
+	case ev.Key() == tcell.KeyUp:
+		panel.row = max(panel.row-1, 0)
+	case ev.Key() == tcell.KeyDown:
+		panel.row = min(panel.row+1, len(faultPanelSignals)-1)
+	case ev.Key() == tcell.KeyLeft:
+		panel.col = max(panel.col-1, 0)
+	case ev.Key() == tcell.KeyRight:
+		panel.col = min(panel.col+1, len(faultPanelColumns)-1)
+	case ev.Rune() == '+' || ev.Rune() == '=':
+		panel.adjust(1)
+	case ev.Rune() == '-':
+		panel.adjust(-1)
+	default:
+		return false
+	}
+	return true
+}
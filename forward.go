@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+)
+
+const (
+	forwardQueueSize = 1000
+	forwardWorkers   = 4
+)
+
+var (
+	errForwardQueueFull = errors.New("forward queue is full, request dropped")
+	errForwarderClosed  = errors.New("forwarder is closed, request dropped")
+)
+
+// Forwarder relays received requests to an upstream endpoint in the background,
+// so the upstream latency doesn't affect the clients of otlprobe.
+type Forwarder struct {
+	exporter Exporter
+	timeout  time.Duration
+	queue    chan func(ctx context.Context) error
+	errs     chan error
+	wg       sync.WaitGroup
+
+	// ctx is cancelled when the queue isn't drained in time on close
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// mu guards closing of the queue, the server may still pass requests
+	mu     sync.RWMutex
+	closed bool
+
+	forwarded atomic.Uint64
+	failed    atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+func newForwarder(exporter Exporter, timeout time.Duration) *Forwarder {
+	f := Forwarder{
+		exporter: exporter,
+		timeout:  timeout,
+		queue:    make(chan func(ctx context.Context) error, forwardQueueSize),
+		errs:     make(chan error, 100),
+	}
+	f.ctx, f.cancel = context.WithCancel(context.Background())
+	for i := 0; i < forwardWorkers; i++ {
+		f.wg.Add(1)
+		go f.work()
+	}
+	return &f
+}
+
+func (f *Forwarder) work() {
+	defer f.wg.Done()
+	for job := range f.queue {
+		if f.ctx.Err() != nil {
+			f.dropped.Add(1)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(f.ctx, f.timeout)
+		err := job(ctx)
+		cancel()
+		if err != nil {
+			f.report(err)
+		} else {
+			f.forwarded.Add(1)
+		}
+	}
+}
+
+// report passes the error to the consumer of errors() without blocking,
+// if nobody keeps up the error is only counted.
+func (f *Forwarder) report(err error) {
+	n := f.failed.Add(1)
+	select {
+	case f.errs <- fmt.Errorf("forward: %w (%d failed)", err, n):
+	default:
+	}
+}
+
+func (f *Forwarder) enqueue(job func(ctx context.Context) error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.closed {
+		f.report(errForwarderClosed)
+		return
+	}
+	select {
+	case f.queue <- job:
+	default:
+		f.report(errForwardQueueFull)
+	}
+}
+
+// errors returns channel with forwarding errors.
+func (f *Forwarder) errors() <-chan error {
+	return f.errs
+}
+
+// HandleMetrics queues the request to be sent upstream, Forwarder is
+// a RequestHandler of the server.
+func (f *Forwarder) HandleMetrics(request pmetricotlp.ExportRequest) {
+	f.enqueue(func(ctx context.Context) error {
+		return f.exporter.exportMetrics(ctx, request)
+	})
+}
+
+func (f *Forwarder) HandleLogs(request plogotlp.ExportRequest) {
+	f.enqueue(func(ctx context.Context) error {
+		return f.exporter.exportLogs(ctx, request)
+	})
+}
+
+func (f *Forwarder) HandleTraces(request ptraceotlp.ExportRequest) {
+	f.enqueue(func(ctx context.Context) error {
+		return f.exporter.exportTraces(ctx, request)
+	})
+}
+
+// close waits for queued requests at most the timeout in total and closes
+// the exporter, requests not sent by then or passed later are dropped.
+func (f *Forwarder) close() error {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return nil
+	}
+	f.closed = true
+	close(f.queue)
+	f.mu.Unlock()
+	timer := time.AfterFunc(f.timeout, f.cancel)
+	f.wg.Wait()
+	timer.Stop()
+	f.cancel()
+	var err error
+	if n := f.dropped.Load(); n > 0 {
+		err = fmt.Errorf("forward: %d queued requests dropped on exit", n)
+	}
+	return errors.Join(err, f.exporter.close())
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tomplus/otlprobe/probe"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+func newTestTraces() ptraceotlp.ExportRequest {
+	td := ptrace.NewTraces()
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("service.name", "checkout")
+	sp := rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	sp.SetName("POST /orders")
+	sp.SetTraceID([16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+	sp.SetSpanID([8]byte{1, 2, 3, 4, 5, 6, 7, 8})
+	return ptraceotlp.NewExportRequestFromTraces(td)
+}
+
+// newUpstream starts gRPC and HTTP receivers backed by otlprobe's own server.
+func newUpstream(t *testing.T) (*probe.Server, chan *probe.Signal, string, string) {
+	ch := make(chan *probe.Signal, 100)
+	upstream := probe.NewServer(0, 0, ch)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go upstream.ServeGRPC(lis)
+	t.Cleanup(upstream.Stop)
+
+	handler := upstream.Handler()
+	hs := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-Tenant") != "acme" || req.Header.Get("Content-Encoding") != "zstd" {
+			http.Error(resp, "invalid headers", http.StatusBadRequest)
+			return
+		}
+		handler.ServeHTTP(resp, req)
+	}))
+	t.Cleanup(hs.Close)
+
+	return upstream, ch, "grpc://" + lis.Addr().String(), hs.URL
+}
+
+func waitForSignal(t *testing.T, ch chan *probe.Signal) *probe.Signal {
+	select {
+	case s := <-ch:
+		return s
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for signal")
+	}
+	return nil
+}
+
+func TestForwarder(t *testing.T) {
+
+	upstream, ch, grpcEndpoint, httpEndpoint := newUpstream(t)
+
+	for _, endpoint := range []string{grpcEndpoint, httpEndpoint} {
+		exporter, err := newExporter(ExporterOptions{
+			endpoint:    endpoint,
+			headers:     map[string]string{"X-Tenant": "acme"},
+			compression: "zstd",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fwd := newForwarder(exporter, 5*time.Second)
+		fwd.HandleTraces(newTestTraces())
+
+		s := waitForSignal(t, ch)
+		if s.Kind != probe.TRACE {
+			t.Errorf("invalid signal forwarded to %v => %v", endpoint, s)
+		}
+		fwd.close()
+		if fwd.forwarded.Load() != 1 || fwd.failed.Load() != 0 {
+			t.Errorf("invalid counters for %v => %d, %d", endpoint, fwd.forwarded.Load(), fwd.failed.Load())
+		}
+	}
+
+	// upstream failures are reported
+	upstream.Faults.Set(probe.TRACE, probe.FaultConfig{ErrorRate: 1, ErrorCode: codes.Unavailable})
+	for _, endpoint := range []string{grpcEndpoint, httpEndpoint} {
+		exporter, _ := newExporter(ExporterOptions{endpoint: endpoint, headers: map[string]string{"X-Tenant": "acme"}, compression: "zstd"})
+		fwd := newForwarder(exporter, 5*time.Second)
+		fwd.HandleTraces(newTestTraces())
+		select {
+		case err := <-fwd.errors():
+			if err == nil {
+				t.Errorf("expected error from %v", endpoint)
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("timeout waiting for error from %v", endpoint)
+		}
+		fwd.close()
+	}
+
+	// queued requests are sent on close, later ones are dropped
+	upstream.Faults.Set(probe.TRACE, probe.FaultConfig{})
+	exporter, _ := newExporter(ExporterOptions{endpoint: grpcEndpoint})
+	fwd := newForwarder(exporter, 5*time.Second)
+	for i := 0; i < 3; i++ {
+		fwd.HandleTraces(newTestTraces())
+	}
+	fwd.close()
+	if fwd.forwarded.Load() != 3 || len(ch) != 3 {
+		t.Errorf("queued requests should be sent on close => %d, %d", fwd.forwarded.Load(), len(ch))
+	}
+	fwd.HandleTraces(newTestTraces())
+	if fwd.failed.Load() != 1 {
+		t.Errorf("request after close should be dropped => %d", fwd.failed.Load())
+	}
+
+}
+
+func TestGrpcExporterHeaders(t *testing.T) {
+
+	e, err := newExporter(ExporterOptions{endpoint: "localhost:4317", headers: map[string]string{"Authorization": "Bearer x"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.close()
+	ge := e.(*grpcExporter)
+	md, _ := metadata.FromOutgoingContext(ge.context(t.Context()))
+	if md.Get("authorization")[0] != "Bearer x" {
+		t.Errorf("invalid metadata => %v", md)
+	}
+
+	if _, err := newExporter(ExporterOptions{endpoint: "ftp://localhost"}); err == nil {
+		t.Errorf("unsupported scheme should fail")
+	}
+	if _, err := newExporter(ExporterOptions{endpoint: "localhost:4317", compression: "lz4"}); err == nil {
+		t.Errorf("unsupported compression should fail")
+	}
+
+}
+
+// stuckExporter waits until the request is cancelled, like an unreachable upstream.
+type stuckExporter struct{}
+
+func (stuckExporter) exportMetrics(ctx context.Context, request pmetricotlp.ExportRequest) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (stuckExporter) exportLogs(ctx context.Context, request plogotlp.ExportRequest) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (stuckExporter) exportTraces(ctx context.Context, request ptraceotlp.ExportRequest) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (stuckExporter) close() error {
+	return nil
+}
+
+func TestForwarderCloseDeadline(t *testing.T) {
+
+	// the queue is drained within one timeout in total, the rest is dropped
+	fwd := newForwarder(stuckExporter{}, 100*time.Millisecond)
+	for i := 0; i < 20; i++ {
+		fwd.HandleTraces(newTestTraces())
+	}
+	start := time.Now()
+	err := fwd.close()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("close should be bounded => %v", elapsed)
+	}
+	dropped := fwd.dropped.Load()
+	if dropped == 0 || dropped+fwd.failed.Load() != 20 || err == nil || !strings.Contains(err.Error(), fmt.Sprintf("%d queued requests dropped", dropped)) {
+		t.Errorf("dropped requests should be reported => %d, %v", dropped, err)
+	}
+
+}
+
+func TestHttpExporterResponseContentType(t *testing.T) {
+
+	// partial success is read even with parameters of the media type
+	hs := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		presp := ptraceotlp.NewExportResponse()
+		presp.PartialSuccess().SetRejectedSpans(1)
+		presp.PartialSuccess().SetErrorMessage("invalid span")
+		data, _ := presp.MarshalProto()
+		resp.Header().Set("Content-Type", probe.ContentTypeProto+"; charset=utf-8")
+		resp.Write(data)
+	}))
+	defer hs.Close()
+	exporter, err := newExporter(ExporterOptions{endpoint: hs.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exporter.close()
+	if err := exporter.exportTraces(context.Background(), newTestTraces()); err == nil || !strings.Contains(err.Error(), "invalid span") {
+		t.Errorf("partial success should be reported => %v", err)
+	}
+
+}
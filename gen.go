@@ -0,0 +1,654 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tomplus/otlprobe/probe"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// GeneratorOptions configure content of the generated data.
+type GeneratorOptions struct {
+	kinds       []probe.KindSignal
+	services    []string
+	cardinality int     // number of routes, i.e. series of the request metrics
+	payloadSize int     // size of an extra attribute of spans and logs
+	errorRate   float64 // probability of a failed operation
+	batch       int     // log records in a request
+}
+
+func defaultGeneratorOptions() GeneratorOptions {
+	return GeneratorOptions{
+		kinds:       []probe.KindSignal{probe.TRACE, probe.LOG, probe.METRIC},
+		services:    []string{"frontend", "checkout", "cart", "payment", "inventory"},
+		cardinality: 8,
+		errorRate:   0.05,
+		batch:       10,
+	}
+}
+
+// Generator produces synthetic traces, logs and metrics. Requests of the
+// configured kinds are produced in turns. It's not safe for concurrent use.
+type Generator struct {
+	opts   GeneratorOptions
+	rng    *rand.Rand
+	routes []string
+	next   int
+
+	// state of cumulative sums
+	start   time.Time
+	cpuTime map[string]float64
+}
+
+var genRoutes = []string{"/", "/api/cart", "/api/checkout", "/api/products", "/api/products/{id}", "/api/orders", "/api/orders/{id}", "/api/recommendations"}
+
+func newGenerator(opts GeneratorOptions, seed uint64) *Generator {
+	g := Generator{
+		opts:    opts,
+		rng:     rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15)),
+		start:   time.Now(),
+		cpuTime: map[string]float64{},
+	}
+	for i := 0; i < max(opts.cardinality, 1); i++ {
+		if i < len(genRoutes) {
+			g.routes = append(g.routes, genRoutes[i])
+		} else {
+			g.routes = append(g.routes, fmt.Sprintf("/api/v1/resource%d", i))
+		}
+	}
+	return &g
+}
+
+// generate returns the next request.
+func (g *Generator) generate(now time.Time) probe.OTLPData {
+	kind := g.opts.kinds[g.next%len(g.opts.kinds)]
+	g.next++
+	switch kind {
+	case probe.LOG:
+		return probe.OTLPData{Kind: probe.LOG, Logs: g.logs(now)}
+	case probe.METRIC:
+		return probe.OTLPData{Kind: probe.METRIC, Metrics: g.metrics(now)}
+	}
+	return probe.OTLPData{Kind: probe.TRACE, Traces: g.traces(now)}
+}
+
+func (g *Generator) pick(values []string) string {
+	return values[g.rng.IntN(len(values))]
+}
+
+func (g *Generator) failed() bool {
+	return g.rng.Float64() < g.opts.errorRate
+}
+
+// duration returns a log-normally distributed duration with the median.
+func (g *Generator) duration(median time.Duration) time.Duration {
+	return time.Duration(float64(median) * math.Exp(g.rng.NormFloat64()*0.6))
+}
+
+func (g *Generator) payload(attrs pcommon.Map) {
+	if g.opts.payloadSize > 0 {
+		attrs.PutStr("gen.payload", strings.Repeat("x", g.opts.payloadSize))
+	}
+}
+
+func (g *Generator) resource(resource pcommon.Resource, service string) {
+	resource.Attributes().PutStr("service.name", service)
+	resource.Attributes().PutStr("service.version", "1.4.2")
+	resource.Attributes().PutStr("deployment.environment.name", "demo")
+	resource.Attributes().PutStr("host.name", service+"-7d9f8b6c5-x2x4q")
+}
+
+func (g *Generator) scope(scope pcommon.InstrumentationScope) {
+	scope.SetName("otlprobe/gen")
+	scope.SetVersion("1.0.0")
+}
+
+// traceBuilder keeps spans of a trace grouped by service.
+type traceBuilder struct {
+	td      ptrace.Traces
+	traceID pcommon.TraceID
+	spans   map[string]ptrace.SpanSlice
+}
+
+func (g *Generator) span(tb *traceBuilder, service string, parent ptrace.Span, name string, kind ptrace.SpanKind, start, end time.Time) ptrace.Span {
+	spans, ok := tb.spans[service]
+	if !ok {
+		rs := tb.td.ResourceSpans().AppendEmpty()
+		g.resource(rs.Resource(), service)
+		ss := rs.ScopeSpans().AppendEmpty()
+		g.scope(ss.Scope())
+		spans = ss.Spans()
+		tb.spans[service] = spans
+	}
+	sp := spans.AppendEmpty()
+	sp.SetTraceID(tb.traceID)
+	var id pcommon.SpanID
+	binary.LittleEndian.PutUint64(id[:], g.rng.Uint64())
+	sp.SetSpanID(id)
+	if parent != (ptrace.Span{}) {
+		sp.SetParentSpanID(parent.SpanID())
+	}
+	sp.SetName(name)
+	sp.SetKind(kind)
+	sp.SetStartTimestamp(pcommon.NewTimestampFromTime(start))
+	sp.SetEndTimestamp(pcommon.NewTimestampFromTime(end))
+	g.payload(sp.Attributes())
+	return sp
+}
+
+func (g *Generator) fail(sp ptrace.Span, errType string, msg string) {
+	sp.Status().SetCode(ptrace.StatusCodeError)
+	sp.Status().SetMessage(msg)
+	ev := sp.Events().AppendEmpty()
+	ev.SetName("exception")
+	ev.SetTimestamp(sp.EndTimestamp())
+	ev.Attributes().PutStr("exception.type", errType)
+	ev.Attributes().PutStr("exception.message", msg)
+}
+
+// traces returns a single trace which starts with a server span and calls
+// databases and other services.
+func (g *Generator) traces(now time.Time) ptrace.Traces {
+	tb := traceBuilder{td: ptrace.NewTraces(), spans: map[string]ptrace.SpanSlice{}}
+	binary.LittleEndian.PutUint64(tb.traceID[:8], g.rng.Uint64())
+	binary.LittleEndian.PutUint64(tb.traceID[8:], g.rng.Uint64())
+
+	service := g.pick(g.opts.services)
+	route := g.pick(g.routes)
+	method := g.pick([]string{"GET", "GET", "GET", "POST", "PUT", "DELETE"})
+	start := now.Add(-g.duration(80 * time.Millisecond))
+	root := g.span(&tb, service, ptrace.Span{}, method+" "+route, ptrace.SpanKindServer, start, now)
+	root.Attributes().PutStr("http.request.method", method)
+	root.Attributes().PutStr("http.route", route)
+	root.Attributes().PutStr("url.path", strings.ReplaceAll(route, "{id}", fmt.Sprint(g.rng.IntN(1000))))
+	if g.children(&tb, root, service, 1) {
+		g.fail(root, "InternalServerError", "request failed")
+		root.Attributes().PutInt("http.response.status_code", 500)
+	} else {
+		root.Attributes().PutInt("http.response.status_code", 200)
+	}
+	return tb.td
+}
+
+// children adds spans sequentially within the parent span, it returns
+// true if any of them failed.
+func (g *Generator) children(tb *traceBuilder, parent ptrace.Span, service string, depth int) bool {
+	start, end := parent.StartTimestamp().AsTime(), parent.EndTimestamp().AsTime()
+	n := g.rng.IntN(4)
+	if depth == 1 {
+		n = max(n, 1)
+	} else if depth > 3 {
+		n = 0
+	}
+	if n == 0 {
+		return false
+	}
+
+	failed := false
+	slot := end.Sub(start) / time.Duration(n)
+	for i := 0; i < n; i++ {
+		s := start.Add(slot * time.Duration(i)).Add(time.Duration(g.rng.Float64() * 0.1 * float64(slot)))
+		e := s.Add(time.Duration((0.3 + g.rng.Float64()*0.6) * float64(slot)))
+		switch g.rng.IntN(3) {
+		case 0:
+			table := g.pick([]string{"orders", "products", "users", "carts"})
+			op := g.pick([]string{"SELECT", "SELECT", "INSERT", "UPDATE"})
+			query := map[string]string{
+				"SELECT": "SELECT * FROM %s WHERE id = $1",
+				"INSERT": "INSERT INTO %s VALUES ($1, $2, $3)",
+				"UPDATE": "UPDATE %s SET updated_at = now() WHERE id = $1",
+			}[op]
+			sp := g.span(tb, service, parent, op+" "+table, ptrace.SpanKindClient, s, e)
+			sp.Attributes().PutStr("db.system.name", "postgresql")
+			sp.Attributes().PutStr("db.operation.name", op)
+			sp.Attributes().PutStr("db.collection.name", table)
+			sp.Attributes().PutStr("db.query.text", fmt.Sprintf(query, table))
+			if g.failed() {
+				g.fail(sp, "TimeoutError", "connection timed out after 5s")
+				failed = true
+			}
+		case 1:
+			callee := g.pick(g.opts.services)
+			route := g.pick(g.routes)
+			client := g.span(tb, service, parent, "POST", ptrace.SpanKindClient, s, e)
+			client.Attributes().PutStr("http.request.method", "POST")
+			client.Attributes().PutStr("server.address", callee)
+			client.Attributes().PutStr("url.full", "http://"+callee+route)
+			// the server span is shorter by the network latency
+			latency := min(time.Duration(g.rng.IntN(2000))*time.Microsecond, e.Sub(s)/4)
+			server := g.span(tb, callee, client, "POST "+route, ptrace.SpanKindServer, s.Add(latency), e.Add(-latency))
+			server.Attributes().PutStr("http.request.method", "POST")
+			server.Attributes().PutStr("http.route", route)
+			status := int64(200)
+			if g.children(tb, server, callee, depth+1) || g.failed() {
+				g.fail(server, "UnavailableError", callee+" is unavailable")
+				client.Status().SetCode(ptrace.StatusCodeError)
+				status = 503
+				failed = true
+			}
+			server.Attributes().PutInt("http.response.status_code", status)
+			client.Attributes().PutInt("http.response.status_code", status)
+		default:
+			sp := g.span(tb, service, parent, g.pick([]string{"validate request", "render", "compute price", "check stock"}), ptrace.SpanKindInternal, s, e)
+			if g.children(tb, sp, service, depth+1) {
+				sp.Status().SetCode(ptrace.StatusCodeError)
+				failed = true
+			}
+		}
+	}
+	return failed
+}
+
+var genMessages = []struct {
+	severity plog.SeverityNumber
+	text     string
+	weight   float64
+	messages []string
+}{
+	{plog.SeverityNumberDebug, "DEBUG", 0.25, []string{"cache hit for key %s", "loaded configuration from %s", "acquired connection from pool for %s"}},
+	{plog.SeverityNumberInfo, "INFO", 0.5, []string{"request completed route=%s", "order placed for %s", "user signed in from %s"}},
+	{plog.SeverityNumberWarn, "WARN", 0.15, []string{"slow request route=%s", "retrying call to %s, attempt 2", "cache miss for key %s"}},
+	{plog.SeverityNumberError, "ERROR", 0.08, []string{"failed to process %s: connection refused", "payment declined for %s", "timeout calling %s"}},
+	{plog.SeverityNumberFatal, "FATAL", 0.02, []string{"out of memory while handling %s", "cannot open database %s"}},
+}
+
+// severity returns index of a random genMessages entry by their weights.
+func (g *Generator) severity() int {
+	r := g.rng.Float64()
+	for i, m := range genMessages {
+		if r -= m.weight; r < 0 {
+			return i
+		}
+	}
+	return len(genMessages) - 1
+}
+
+// logs returns a batch of log records of random services and severities.
+func (g *Generator) logs(now time.Time) plog.Logs {
+	ld := plog.NewLogs()
+	records := map[string]plog.LogRecordSlice{}
+	for i := 0; i < max(g.opts.batch, 1); i++ {
+		service := g.pick(g.opts.services)
+		lrs, ok := records[service]
+		if !ok {
+			rl := ld.ResourceLogs().AppendEmpty()
+			g.resource(rl.Resource(), service)
+			sl := rl.ScopeLogs().AppendEmpty()
+			g.scope(sl.Scope())
+			lrs = sl.LogRecords()
+			records[service] = lrs
+		}
+
+		m := genMessages[g.severity()]
+		ts := now.Add(-time.Duration(g.rng.IntN(1000)) * time.Millisecond)
+		lr := lrs.AppendEmpty()
+		lr.SetTimestamp(pcommon.NewTimestampFromTime(ts))
+		lr.SetObservedTimestamp(pcommon.NewTimestampFromTime(now))
+		lr.SetSeverityNumber(m.severity)
+		lr.SetSeverityText(m.text)
+		lr.Body().SetStr(fmt.Sprintf(g.pick(m.messages), g.pick(g.routes)))
+		lr.Attributes().PutStr("code.function.name", g.pick([]string{"handleRequest", "placeOrder", "loadCart", "charge"}))
+		if g.rng.IntN(2) == 0 {
+			var traceID pcommon.TraceID
+			var spanID pcommon.SpanID
+			binary.LittleEndian.PutUint64(traceID[:8], g.rng.Uint64())
+			binary.LittleEndian.PutUint64(traceID[8:], g.rng.Uint64())
+			binary.LittleEndian.PutUint64(spanID[:], g.rng.Uint64())
+			lr.SetTraceID(traceID)
+			lr.SetSpanID(spanID)
+		}
+		g.payload(lr.Attributes())
+	}
+	return ld
+}
+
+// metrics returns one collection of every service with all types of metrics.
+// Histograms are delta, sums are cumulative.
+func (g *Generator) metrics(now time.Time) pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	ts := pcommon.NewTimestampFromTime(now)
+	start := pcommon.NewTimestampFromTime(g.start)
+	for _, service := range g.opts.services {
+		rm := md.ResourceMetrics().AppendEmpty()
+		g.resource(rm.Resource(), service)
+		sm := rm.ScopeMetrics().AppendEmpty()
+		g.scope(sm.Scope())
+
+		m := sm.Metrics().AppendEmpty()
+		m.SetName("process.memory.usage")
+		m.SetDescription("The amount of physical memory in use.")
+		m.SetUnit("By")
+		dp := m.SetEmptyGauge().DataPoints().AppendEmpty()
+		dp.SetTimestamp(ts)
+		dp.SetIntValue(int64(200<<20 + g.rng.IntN(50<<20)))
+
+		m = sm.Metrics().AppendEmpty()
+		m.SetName("process.cpu.time")
+		m.SetDescription("Total CPU seconds broken down by different states.")
+		m.SetUnit("s")
+		sum := m.SetEmptySum()
+		sum.SetIsMonotonic(true)
+		sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+		for _, mode := range []string{"user", "system"} {
+			key := service + "/" + mode
+			g.cpuTime[key] += g.rng.Float64() * 0.5
+			dp := sum.DataPoints().AppendEmpty()
+			dp.SetStartTimestamp(start)
+			dp.SetTimestamp(ts)
+			dp.SetDoubleValue(g.cpuTime[key])
+			dp.Attributes().PutStr("cpu.mode", mode)
+		}
+
+		m = sm.Metrics().AppendEmpty()
+		m.SetName("http.server.request.duration")
+		m.SetDescription("Duration of HTTP server requests.")
+		m.SetUnit("s")
+		hist := m.SetEmptyHistogram()
+		hist.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+		bounds := []float64{0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10}
+		for _, route := range g.routes {
+			dp := hist.DataPoints().AppendEmpty()
+			dp.SetStartTimestamp(ts - pcommon.Timestamp(10*time.Second))
+			dp.SetTimestamp(ts)
+			dp.Attributes().PutStr("http.request.method", "GET")
+			dp.Attributes().PutStr("http.route", route)
+			dp.Attributes().PutInt("http.response.status_code", 200)
+			dp.ExplicitBounds().FromRaw(bounds)
+			counts := make([]uint64, len(bounds)+1)
+			for _, v := range g.samples(0.08) {
+				counts[sort.SearchFloat64s(bounds, v)]++
+				dp.SetCount(dp.Count() + 1)
+				dp.SetSum(dp.Sum() + v)
+				if dp.Count() == 1 || v < dp.Min() {
+					dp.SetMin(v)
+				}
+				dp.SetMax(max(dp.Max(), v))
+			}
+			dp.BucketCounts().FromRaw(counts)
+		}
+
+		m = sm.Metrics().AppendEmpty()
+		m.SetName("rpc.server.duration")
+		m.SetDescription("Measures the duration of inbound RPC.")
+		m.SetUnit("ms")
+		exp := m.SetEmptyExponentialHistogram()
+		exp.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+		for _, method := range []string{"GetCart", "PlaceOrder"} {
+			dp := exp.DataPoints().AppendEmpty()
+			dp.SetStartTimestamp(ts - pcommon.Timestamp(10*time.Second))
+			dp.SetTimestamp(ts)
+			dp.Attributes().PutStr("rpc.system", "grpc")
+			dp.Attributes().PutStr("rpc.service", service)
+			dp.Attributes().PutStr("rpc.method", method)
+			g.exponentialBuckets(dp, g.samples(20))
+		}
+
+		m = sm.Metrics().AppendEmpty()
+		m.SetName("app.request.latency")
+		m.SetDescription("Latency of requests (legacy summary).")
+		m.SetUnit("ms")
+		sdp := m.SetEmptySummary().DataPoints().AppendEmpty()
+		sdp.SetStartTimestamp(start)
+		sdp.SetTimestamp(ts)
+		samples := g.samples(50)
+		sort.Float64s(samples)
+		for _, v := range samples {
+			sdp.SetSum(sdp.Sum() + v)
+		}
+		sdp.SetCount(uint64(len(samples)))
+		for _, q := range []float64{0.5, 0.9, 0.99} {
+			qv := sdp.QuantileValues().AppendEmpty()
+			qv.SetQuantile(q)
+			if len(samples) > 0 {
+				qv.SetValue(samples[min(int(q*float64(len(samples))), len(samples)-1)])
+			}
+		}
+	}
+	return md
+}
+
+// samples returns a few log-normally distributed values with the median.
+func (g *Generator) samples(median float64) []float64 {
+	res := make([]float64, 1+g.rng.IntN(20))
+	for i := range res {
+		res[i] = median * math.Exp(g.rng.NormFloat64()*0.6)
+	}
+	return res
+}
+
+// exponentialBuckets fills the data point at scale 2, i.e. the bucket
+// boundaries grow by 2^(1/4).
+func (g *Generator) exponentialBuckets(dp pmetric.ExponentialHistogramDataPoint, samples []float64) {
+	const scale = 2
+	dp.SetScale(scale)
+	indexes := make([]int, len(samples))
+	lowest := math.MaxInt
+	for i, v := range samples {
+		indexes[i] = int(math.Ceil(math.Log2(v)*(1<<scale))) - 1
+		lowest = min(lowest, indexes[i])
+		dp.SetSum(dp.Sum() + v)
+		if i == 0 || v < dp.Min() {
+			dp.SetMin(v)
+		}
+		dp.SetMax(max(dp.Max(), v))
+	}
+	dp.SetCount(uint64(len(samples)))
+	if len(samples) == 0 {
+		return
+	}
+	dp.Positive().SetOffset(int32(lowest))
+	for _, index := range indexes {
+		for dp.Positive().BucketCounts().Len() <= index-lowest {
+			dp.Positive().BucketCounts().Append(0)
+		}
+		bc := dp.Positive().BucketCounts()
+		bc.SetAt(index-lowest, bc.At(index-lowest)+1)
+	}
+}
+
+// rateInterval returns the interval between requests sent at the rate per
+// second, at least 1ns as the ticker doesn't accept shorter ones.
+func rateInterval(rate float64) time.Duration {
+	return max(time.Duration(float64(time.Second)/rate), time.Nanosecond)
+}
+
+// demoRate is the number of requests per second of the demo mode.
+const demoRate = 5
+
+// runDemo feeds generated data to the server in-process, so the UI can be
+// tried without an instrumented application.
+func runDemo(ctx context.Context, server *probe.Server, rate float64) {
+	g := newGenerator(defaultGeneratorOptions(), uint64(time.Now().UnixNano()))
+	ticker := time.NewTicker(rateInterval(rate))
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			if err := server.Process(ctx, g.generate(now)); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runGen sends generated data to an OTLP endpoint.
+func runGen(args []string) {
+
+	fs := flag.NewFlagSet("gen", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: otlprobe gen --to <endpoint> [options]\n\n")
+		fs.PrintDefaults()
+	}
+	opts := newExporterOptions()
+	fs.StringVar(&opts.endpoint, "to", "", "endpoint receiving the requests, e.g. localhost:4317, grpc://collector:4317 or https://collector:4318")
+	opts.register(fs, "", "generated requests")
+	genOpts := defaultGeneratorOptions()
+	signalsPtr := fs.String("signals", "traces,logs,metrics", "kinds of generated signals")
+	servicesPtr := fs.String("services", strings.Join(genOpts.services, ","), "names of services")
+	fs.IntVar(&genOpts.cardinality, "cardinality", genOpts.cardinality, "number of HTTP routes, i.e. series of the request duration histogram per service")
+	var payloadSize byteSize
+	fs.Var(&payloadSize, "payload-size", "size of an extra attribute added to spans and logs, e.g. 1KB")
+	fs.Float64Var(&genOpts.errorRate, "error-rate", genOpts.errorRate, "probability of a failed operation")
+	fs.IntVar(&genOpts.batch, "batch", genOpts.batch, "number of log records in a request")
+	ratePtr := fs.Float64("rate", 10, "requests per second")
+	durationPtr := fs.Duration("duration", 0, "stop after the given time (default until interrupted)")
+	workersPtr := fs.Int("workers", 4, "number of concurrent requests")
+	seedPtr := fs.Uint64("seed", 0, "seed of the random generator (default random)")
+	timeoutPtr := fs.Duration("timeout", 10*time.Second, "timeout of exported requests")
+	fs.Parse(args)
+	if opts.endpoint == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	kinds, err := parseKinds(*signalsPtr)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	genOpts.kinds = kinds
+	genOpts.services = strings.Split(*servicesPtr, ",")
+	genOpts.payloadSize = int(payloadSize)
+	if *ratePtr <= 0 || *workersPtr < 1 || genOpts.cardinality < 1 || genOpts.batch < 1 || *servicesPtr == "" {
+		log.Fatalln("rate, workers, cardinality, batch and services must be positive")
+	}
+	seed := *seedPtr
+	if seed == 0 {
+		seed = uint64(time.Now().UnixNano())
+	}
+	exporter, err := newExporter(*opts)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *durationPtr > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *durationPtr)
+		defer cancel()
+	}
+
+	var sent, signals, failed atomic.Uint64
+	failures := genFailures{counts: make(map[string]int)}
+	stats := func() string {
+		return fmt.Sprintf("sent %d requests (%d signals), %d failed", sent.Load(), signals.Load(), failed.Load())
+	}
+	jobs := make(chan probe.OTLPData)
+	var wg sync.WaitGroup
+	for i := 0; i < *workersPtr; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for d := range jobs {
+				ectx, cancel := context.WithTimeout(context.Background(), *timeoutPtr)
+				err := exportData(ectx, exporter, d)
+				cancel()
+				if err != nil {
+					failed.Add(1)
+					failures.add(err)
+					continue
+				}
+				sent.Add(1)
+				d.Signals(func(s *probe.Signal) error {
+					signals.Add(1)
+					return nil
+				})
+			}
+		}()
+	}
+
+	g := newGenerator(genOpts, seed)
+	ticker := time.NewTicker(rateInterval(*ratePtr))
+	report := time.NewTicker(5 * time.Second)
+loop:
+	for {
+		select {
+		case now := <-ticker.C:
+			select {
+			case jobs <- g.generate(now):
+			case <-ctx.Done():
+				break loop
+			}
+		case <-report.C:
+			fmt.Fprintln(os.Stderr, stats())
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	ticker.Stop()
+	report.Stop()
+	close(jobs)
+	wg.Wait()
+	exporter.close()
+	fmt.Fprintln(os.Stderr, stats())
+	failures.write(os.Stderr)
+	if failed.Load() > 0 {
+		os.Exit(1)
+	}
+}
+
+// genKinds are the names of generated kinds of signals.
+var genKinds = map[string]probe.KindSignal{
+	"logs":    probe.LOG,
+	"metrics": probe.METRIC,
+	"traces":  probe.TRACE,
+}
+
+// parseKinds parses comma separated kinds of signals, e.g. traces,logs.
+func parseKinds(s string) ([]probe.KindSignal, error) {
+	kinds := make([]probe.KindSignal, 0, 3)
+	for _, name := range strings.Split(s, ",") {
+		k, ok := genKinds[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown signal %q, expected traces, logs or metrics", name)
+		}
+		kinds = append(kinds, k)
+	}
+	return kinds, nil
+}
+
+// genFailures counts failed requests by the error, every distinct error is
+// logged once.
+type genFailures struct {
+	mu     sync.Mutex
+	counts map[string]int
+	order  []string
+}
+
+func (f *genFailures) add(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	msg := err.Error()
+	if f.counts[msg] == 0 {
+		log.Printf("gen: %v", err)
+		f.order = append(f.order, msg)
+	}
+	f.counts[msg]++
+}
+
+// write prints the number of failed requests of every error.
+func (f *genFailures) write(w io.Writer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, msg := range f.order {
+		fmt.Fprintf(w, "%8d  %s\n", f.counts[msg], msg)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tomplus/otlprobe/probe"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func TestGeneratorTraces(t *testing.T) {
+
+	opts := defaultGeneratorOptions()
+	opts.errorRate = 1
+	opts.payloadSize = 100
+	g := newGenerator(opts, 1)
+	now := time.Now()
+
+	for i := 0; i < 20; i++ {
+		d := g.generate(now)
+		if d.Kind != [3]probe.KindSignal{probe.TRACE, probe.LOG, probe.METRIC}[i%3] {
+			t.Fatalf("invalid kind of request %d => %v", i, d.Kind)
+		}
+		if d.Kind != probe.TRACE {
+			continue
+		}
+		spans := make([]*probe.Signal, 0)
+		d.Signals(func(s *probe.Signal) error {
+			spans = append(spans, s)
+			return nil
+		})
+		roots := buildSpanTree(spans)
+		if len(spans) < 2 || len(roots) != 1 || roots[0].signal == nil || roots[0].end != pcommon.NewTimestampFromTime(now) {
+			t.Fatalf("invalid trace => %d spans, %d roots", len(spans), len(roots))
+		}
+		if roots[0].signal.Span.Status().Code() != ptrace.StatusCodeError {
+			t.Errorf("failure should be propagated to the root span")
+		}
+		var walk func(n *spanNode)
+		walk = func(n *spanNode) {
+			if n.signal.Span.TraceID() != roots[0].signal.Span.TraceID() {
+				t.Errorf("invalid trace ID => %v", n.signal.Span.TraceID())
+			}
+			if p, ok := n.signal.Span.Attributes().Get("gen.payload"); !ok || len(p.Str()) != 100 {
+				t.Errorf("missing payload of %v", n.signal.Span.Name())
+			}
+			for _, c := range n.children {
+				if c.start < n.start || c.end > n.end {
+					t.Errorf("child %v is out of its parent %v", c.signal.Span.Name(), n.signal.Span.Name())
+				}
+				walk(c)
+			}
+		}
+		walk(roots[0])
+	}
+
+	// the same seed gives the same data
+	a, _ := (&ptrace.ProtoMarshaler{}).MarshalTraces(newGenerator(opts, 7).traces(now))
+	b, _ := (&ptrace.ProtoMarshaler{}).MarshalTraces(newGenerator(opts, 7).traces(now))
+	if string(a) != string(b) {
+		t.Errorf("generator should be deterministic")
+	}
+
+}
+
+func TestGeneratorLogs(t *testing.T) {
+
+	opts := defaultGeneratorOptions()
+	opts.batch = 200
+	ld := newGenerator(opts, 1).logs(time.Now())
+	if ld.LogRecordCount() != 200 {
+		t.Errorf("invalid number of records => %d", ld.LogRecordCount())
+	}
+	severities := map[string]int{}
+	probe.LogSignals(ld, func(s *probe.Signal) error {
+		severities[s.Record.SeverityText()]++
+		if s.Record.Body().Str() == "" || s.Record.SeverityNumber() == 0 {
+			t.Errorf("invalid record => %v", s.Summary())
+		}
+		return nil
+	})
+	if len(severities) < 4 || severities["INFO"] < severities["ERROR"] {
+		t.Errorf("invalid severities => %v", severities)
+	}
+
+}
+
+func TestGeneratorMetrics(t *testing.T) {
+
+	opts := defaultGeneratorOptions()
+	opts.services = []string{"cart"}
+	opts.cardinality = 12
+	g := newGenerator(opts, 1)
+	g.metrics(time.Now())
+	md := g.metrics(time.Now())
+
+	types := map[pmetric.MetricType]int{}
+	ms := md.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	for i := 0; i < ms.Len(); i++ {
+		m := ms.At(i)
+		types[m.Type()]++
+		switch m.Type() {
+		case pmetric.MetricTypeSum:
+			if !isMonotonicCumulative(m) || m.Sum().DataPoints().At(0).DoubleValue() <= 0 {
+				t.Errorf("invalid sum => %v", m.Name())
+			}
+		case pmetric.MetricTypeHistogram:
+			if m.Histogram().DataPoints().Len() != 12 {
+				t.Errorf("invalid cardinality => %d", m.Histogram().DataPoints().Len())
+			}
+			dp := m.Histogram().DataPoints().At(0)
+			total := uint64(0)
+			for _, c := range dp.BucketCounts().AsRaw() {
+				total += c
+			}
+			if total != dp.Count() || dp.BucketCounts().Len() != dp.ExplicitBounds().Len()+1 || dp.Min() > dp.Max() {
+				t.Errorf("invalid histogram => %v", m.Name())
+			}
+		case pmetric.MetricTypeExponentialHistogram:
+			dp := m.ExponentialHistogram().DataPoints().At(0)
+			total := uint64(0)
+			for _, c := range dp.Positive().BucketCounts().AsRaw() {
+				total += c
+			}
+			if total != dp.Count() {
+				t.Errorf("invalid exponential histogram => %v", m.Name())
+			}
+		case pmetric.MetricTypeSummary:
+			if m.Summary().DataPoints().At(0).QuantileValues().Len() != 3 {
+				t.Errorf("invalid summary => %v", m.Name())
+			}
+		}
+	}
+	if len(types) != 5 {
+		t.Errorf("all types of metrics should be generated => %v", types)
+	}
+
+}
+
+func TestParseKinds(t *testing.T) {
+
+	if kinds, err := parseKinds("traces,logs"); err != nil || len(kinds) != 2 || kinds[0] != probe.TRACE || kinds[1] != probe.LOG {
+		t.Errorf("invalid kinds => %v, %v", kinds, err)
+	}
+	for _, s := range []string{"traces,profiles", "all"} {
+		if _, err := parseKinds(s); err == nil {
+			t.Errorf("unknown kind %q should fail", s)
+		}
+	}
+
+}
+
+func TestGenFailures(t *testing.T) {
+
+	f := genFailures{counts: make(map[string]int)}
+	for _, msg := range []string{"unavailable", "timeout", "unavailable"} {
+		f.add(errors.New(msg))
+	}
+	var buf bytes.Buffer
+	f.write(&buf)
+	if buf.String() != "       2  unavailable\n       1  timeout\n" {
+		t.Errorf("invalid failures => %q", buf.String())
+	}
+
+}
+
+func TestRateInterval(t *testing.T) {
+
+	for rate, expected := range map[float64]time.Duration{
+		100:  10 * time.Millisecond,
+		0.5:  2 * time.Second,
+		1e9:  time.Nanosecond,
+		1e12: time.Nanosecond,
+	} {
+		if d := rateInterval(rate); d != expected {
+			t.Errorf("invalid interval of rate %v => %v", rate, d)
+		}
+	}
+
+}
+
+func TestDemo(t *testing.T) {
+
+	ch := make(chan *probe.Signal)
+	server := probe.NewServer(0, 0, ch)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan bool)
+	go func() {
+		runDemo(ctx, server, 100)
+		done <- true
+	}()
+	kinds := map[probe.KindSignal]bool{}
+	for len(kinds) < 3 {
+		kinds[waitForSignal(t, ch).Kind] = true
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("demo should stop")
+	}
+
+}
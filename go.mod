@@ -1,4 +1,4 @@
-module otlprobe
+module github.com/tomplus/otlprobe
 
 go 1.24.0
 
@@ -6,6 +6,7 @@ toolchain go1.24.1
 
 require (
 	github.com/gdamore/tcell/v2 v2.7.4
+	github.com/klauspost/compress v1.18.0
 	google.golang.org/grpc v1.79.3
 )
 
@@ -27,6 +28,6 @@ require (
 	golang.org/x/sys v0.39.0 // indirect
 	golang.org/x/term v0.38.0 // indirect
 	golang.org/x/text v0.32.0 // indirect
-	google.golang.org/genproto/googleapis/rpc v0.0.0-20251202230838-ff82c1b0f217 // indirect
-	google.golang.org/protobuf v1.36.10 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20251202230838-ff82c1b0f217
+	google.golang.org/protobuf v1.36.10
 )
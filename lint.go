@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/tomplus/otlprobe/probe"
+)
+
+// lintMarker prefixes rows of signals with lint violations.
+const lintMarker = "⚠ "
+
+// LintOptions are flags of the semantic-convention linter shared by the commands.
+type LintOptions struct {
+	enabled bool
+	version string
+}
+
+func (opts *LintOptions) register(fs *flag.FlagSet) {
+	fs.BoolVar(&opts.enabled, "lint", false, "check signals against the OpenTelemetry semantic conventions")
+	fs.StringVar(&opts.version, "semconv-version", "", "version of the semantic conventions for --lint: "+strings.Join(probe.SemconvVersions(), ", ")+" (default the latest)")
+}
+
+// newLinter returns nil if linting is disabled.
+func (opts *LintOptions) newLinter() (*probe.Linter, error) {
+	if !opts.enabled {
+		if opts.version != "" {
+			return nil, fmt.Errorf("--semconv-version requires --lint")
+		}
+		return nil, nil
+	}
+	return probe.NewLinter(opts.version)
+}
+
+// LintReport counts violations of the signals, e.g. to summarize them on exit
+// of the non-interactive mode.
+type LintReport struct {
+	version  string
+	signals  int
+	violated int
+	counts   map[string]int
+}
+
+func newLintReport(linter *probe.Linter) *LintReport {
+	r := LintReport{version: linter.Version(), counts: make(map[string]int)}
+	return &r
+}
+
+func (r *LintReport) observe(s *probe.Signal) {
+	r.signals++
+	if len(s.Lint) > 0 {
+		r.violated++
+	}
+	for _, v := range s.Lint {
+		r.counts[v.Rule+"\t"+v.Message]++
+	}
+}
+
+// write prints violations with the number of occurrences, the most frequent first.
+func (r *LintReport) write(w io.Writer) {
+	fmt.Fprintf(w, "lint (semconv %s): %d of %d signals with violations\n", r.version, r.violated, r.signals)
+	keys := make([]string, 0, len(r.counts))
+	for k := range r.counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if r.counts[keys[i]] != r.counts[keys[j]] {
+			return r.counts[keys[i]] > r.counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	for _, k := range keys {
+		rule, msg, _ := strings.Cut(k, "\t")
+		fmt.Fprintf(w, "%8d  %-10s  %s\n", r.counts[k], rule, msg)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tomplus/otlprobe/probe"
+)
+
+func TestLintOptions(t *testing.T) {
+
+	if l, err := (&LintOptions{}).newLinter(); l != nil || err != nil {
+		t.Errorf("linter should be disabled => %v, %v", l, err)
+	}
+	if _, err := (&LintOptions{version: "1.20.0"}).newLinter(); err == nil {
+		t.Errorf("version without --lint should fail")
+	}
+	if _, err := (&LintOptions{enabled: true, version: "9.9.9"}).newLinter(); err == nil {
+		t.Errorf("unsupported version should fail")
+	}
+	l, err := (&LintOptions{enabled: true}).newLinter()
+	if err != nil || l.Version() != "1.26.0" {
+		t.Errorf("invalid default linter => %v, %v", l, err)
+	}
+
+}
+
+func TestLintReport(t *testing.T) {
+
+	l, _ := probe.NewLinter("1.26.0")
+	report := newLintReport(l)
+	deprecated := probe.Violation{Rule: probe.LintDeprecated, Key: "http.method", Message: "span attribute http.method is deprecated, use http.request.method"}
+	missing := probe.Violation{Rule: probe.LintMissing, Key: "service.name", Message: "resource attribute service.name is missing"}
+	report.observe(&probe.Signal{Lint: []probe.Violation{deprecated, missing}})
+	report.observe(&probe.Signal{Lint: []probe.Violation{missing}})
+	report.observe(&probe.Signal{})
+
+	var buf bytes.Buffer
+	report.write(&buf)
+	expected := "lint (semconv 1.26.0): 2 of 3 signals with violations\n" +
+		"       2  missing     resource attribute service.name is missing\n" +
+		"       1  deprecated  span attribute http.method is deprecated, use http.request.method\n"
+	if buf.String() != expected {
+		t.Errorf("invalid report => %q", buf.String())
+	}
+
+}
@@ -1,26 +1,82 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
+	"github.com/tomplus/otlprobe/probe"
 )
 
 var screen tcell.Screen
 
-var bucket Bucket = newBucketFixedSize(100)
-
 func main() {
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "view":
+			runView(os.Args[2:])
+			return
+		case "replay":
+			runReplay(os.Args[2:])
+			return
+		case "gen":
+			runGen(os.Args[2:])
+			return
+		case "expect":
+			runExpect(os.Args[2:])
+			return
+		}
+	}
+
 	grpcPortPtr := flag.Int("grpc-port", 4317, "port for gRPC server (default 4317)")
 	grpcDisablePtr := flag.Bool("disable-grpc", false, "disable gRPC server")
 	httpPortPtr := flag.Int("http-port", 4318, "port for HTTP server (default 4318)")
 	httpDisablePtr := flag.Bool("disable-http", false, "disable HTTP server")
-	filterPtr := flag.String("filter", "", "filter for incomming data")
+	filterPtr := flag.String("filter", "", "filter for incomming data, e.g. 'kind:log severity>=WARN' or 'span.duration>500ms'")
+	var bucketOpts BucketOptions
+	bucketOpts.register(flag.CommandLine)
+	var lintOpts LintOptions
+	lintOpts.register(flag.CommandLine)
 	noninteractivePtr := flag.Bool("non-interactive", false, "print out data to stdout (without TUI)")
+	outputPtr := flag.String("output", "text", "format of the non-interactive mode: text, jsonl, otlp-json, logfmt or template=<go template>")
+	colorPtr := flag.String("color", "auto", "colorize the non-interactive output: auto (when stdout is a terminal), always or never")
+	outputFilePtr := flag.String("output-file", "", "write the non-interactive output to the file instead of stdout")
+	outputFileSize := byteSize(100 << 20)
+	flag.Var(&outputFileSize, "output-file-size", "size of the output file before it is rotated, e.g. 100MiB")
+	outputFileKeepPtr := flag.Int("output-file-keep", 5, "number of rotated output files to keep")
+	maxRequestSize := byteSize(probe.DefaultMaxRequestSize)
+	flag.Var(&maxRequestSize, "max-request-size", "max size of (uncompressed) request payload, e.g. 4MiB")
+	var tlsOpts TLSOptions
+	flag.StringVar(&tlsOpts.certFile, "tls-cert", "", "server certificate file (PEM), enables TLS")
+	flag.StringVar(&tlsOpts.keyFile, "tls-key", "", "server private key file (PEM)")
+	flag.StringVar(&tlsOpts.clientCAFile, "tls-client-ca", "", "CA file (PEM) to verify client certificates, enables mTLS")
+	flag.BoolVar(&tlsOpts.selfSigned, "tls-self-signed", false, "generate a self-signed certificate on startup (dev mode)")
+	faults := probe.NewFaultInjector()
+	flag.Func("fault", "inject faults, [signal:]key=value,... e.g. traces:error-rate=0.2,latency=100ms (repeatable)", faults.ParseFaultSpec)
+	fwdOpts := newExporterOptions()
+	flag.StringVar(&fwdOpts.endpoint, "forward", "", "forward received data to upstream endpoint, e.g. grpc://collector:4317 or https://collector:4318")
+	fwdOpts.register(flag.CommandLine, "forward-", "forwarded requests")
+	demoPtr := flag.Bool("demo", false, "generate synthetic signals in-process, e.g. to try the UI")
+	apiAddrPtr := flag.String("api", "", "serve the query API of buffered signals on the address, e.g. localhost:8081")
+	webAddrPtr := flag.String("web", "", "serve the web UI on the address, e.g. :8080")
+	forwardTimeoutPtr := flag.Duration("forward-timeout", 10*time.Second, "timeout of forwarded requests")
+	captureDirPtr := flag.String("capture-dir", "", "append received requests to files in the directory and recover them on restart")
+	captureSegmentSize := byteSize(defaultCaptureSegmentSize)
+	flag.Var(&captureSegmentSize, "capture-segment-size", "size of a capture file before a new one is started, e.g. 64MiB")
+	var captureMaxSize byteSize
+	flag.Var(&captureMaxSize, "capture-max-size", "remove the oldest capture files above the total size, e.g. 10GB")
 	flag.Parse()
 
 	grpcPort, httpPort := 0, 0
@@ -36,22 +92,238 @@ func main() {
 	if grpcPort < 0 || httpPort < 0 {
 		log.Fatalln("Invalid port number")
 	}
+	bucket, err := bucketOpts.newBucket(flag.CommandLine)
+	if err != nil {
+		log.Fatalln(err)
+	}
 
-	chSignal := make(chan *Signal)
-	server := newServer(grpcPort, httpPort, chSignal)
-	go server.start()
+	filter, err := parseQuery(*filterPtr)
+	if err != nil {
+		log.Fatalf("invalid filter: %v", err)
+	}
+	linter, err := lintOpts.newLinter()
+	if err != nil {
+		log.Fatalln(err)
+	}
 
+	var output *Output
 	if *noninteractivePtr {
+		var w io.Writer = os.Stdout
+		color, err := useColor(*colorPtr, os.Stdout)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if *outputFilePtr != "" {
+			file, err := openRotatingFile(*outputFilePtr, int64(outputFileSize), *outputFileKeepPtr)
+			if err != nil {
+				log.Fatalf("failed to open output file: %v", err)
+			}
+			defer file.Close()
+			w, color = file, *colorPtr == "always"
+		}
+		output, err = newOutput(w, *outputPtr, color)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	tlsConfig, fingerprint, err := newServerTLSConfig(tlsOpts)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	// the TUI clears the screen, so it shows the fingerprint in the status bar
+	if fingerprint != "" && *noninteractivePtr {
+		fmt.Fprintf(os.Stderr, "self-signed certificate SHA-256 fingerprint: %s\n", fingerprint)
+	}
+
+	// recovered before the new capture file is started, signals outside
+	// of the retention period would be evicted anyway
+	recovered := 0
+	if *captureDirPtr != "" && !*noninteractivePtr {
+		since := time.Time{}
+		if bucketOpts.retention > 0 {
+			since = time.Now().Add(-bucketOpts.retention)
+		}
+		recovered, err = recoverCapture(*captureDirPtr, bucket, filter, linter, since, bucketOpts.capacity(flag.CommandLine))
+		if err != nil {
+			log.Printf("failed to recover capture: %v", err)
+		}
+	}
+
+	chSignal := make(chan *probe.Signal)
+	server := probe.NewServer(grpcPort, httpPort, chSignal)
+	server.TLSConfig = tlsConfig
+	server.MaxRequestSize = int64(maxRequestSize)
+	server.Faults = faults
+	server.Linter = linter
+	errs := make([]<-chan error, 0, 2)
+	if fwdOpts.endpoint != "" {
+		exporter, err := newExporter(*fwdOpts)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		forwarder := newForwarder(exporter, *forwardTimeoutPtr)
+		// queued requests are sent before exit, both of the TUI and the non-interactive mode
+		defer func() {
+			if err := forwarder.close(); err != nil {
+				log.Println(err)
+			}
+		}()
+		server.Handlers = append(server.Handlers, forwarder)
+		errs = append(errs, forwarder.errors())
+	}
+	if *captureDirPtr != "" {
+		if captureSegmentSize <= 0 {
+			log.Fatalln("Invalid capture segment size")
+		}
+		capture, err := newCapture(*captureDirPtr, int64(captureSegmentSize), int64(captureMaxSize))
+		if err != nil {
+			log.Fatalf("failed to start capture: %v", err)
+		}
+		defer capture.close()
+		server.Handlers = append(server.Handlers, capture)
+		errs = append(errs, capture.errors())
+	}
+
+	signals := chSignal
+	var api *API
+	if *apiAddrPtr != "" || *webAddrPtr != "" {
+		// its own bucket, so the API doesn't depend on what the TUI shows
+		apiBucket, _ := bucketOpts.newBucket(flag.CommandLine)
+		api = newAPI(apiBucket, filter)
+		signals = api.tee(chSignal)
+	}
+	if *apiAddrPtr != "" {
+		lis, err := net.Listen("tcp", *apiAddrPtr)
+		if err != nil {
+			log.Fatalf("failed to start API: %v", err)
+		}
+		go func() {
+			if err := http.Serve(lis, api.handler()); err != nil {
+				log.Printf("API stopped: %v", err)
+			}
+		}()
+	}
+	if *webAddrPtr != "" {
+		lis, err := net.Listen("tcp", *webAddrPtr)
+		if err != nil {
+			log.Fatalf("failed to start web UI: %v", err)
+		}
+		go func() {
+			if err := http.Serve(lis, webHandler(api)); err != nil {
+				log.Printf("web UI stopped: %v", err)
+			}
+		}()
+	}
+
+	go server.Start()
+	if *demoPtr {
+		go runDemo(context.Background(), server, demoRate)
+	}
+
+	if *noninteractivePtr {
+		for _, ch := range errs {
+			go func() {
+				for err := range ch {
+					log.Println(err)
+				}
+			}()
+		}
+		var report *LintReport
+		if linter != nil {
+			report = newLintReport(linter)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
 		i := 0
-		for c := range chSignal {
-			i++
-			if strings.Contains(c.description, *filterPtr) || strings.Contains(c.summary, *filterPtr) {
-				fmt.Printf("%v: %v %v\n", i, c.time.AsTime().String()[0:23], c.summary)
+		for {
+			select {
+			case c := <-signals:
+				i++
+				if !filter.match(c) {
+					continue
+				}
+				if report != nil {
+					report.observe(c)
+				}
+				if err := output.write(i, c); err != nil {
+					log.Println(err)
+				}
+			case <-ctx.Done():
+				// the report is printed when the probe is stopped, e.g. with Ctrl+C
+				if report != nil {
+					report.write(os.Stderr)
+				}
+				return
 			}
 		}
-		return
 	}
 
+	runTUI(bucket, filter, signals, server.Faults, func(browser *Browser) {
+		msgs := make([]string, 0, 2)
+		if fingerprint != "" {
+			msgs = append(msgs, "self-signed certificate SHA-256 fingerprint: "+fingerprint)
+		}
+		if recovered > 0 {
+			msgs = append(msgs, fmt.Sprintf("recovered %d signals from %s", recovered, *captureDirPtr))
+		}
+		if len(msgs) > 0 {
+			browser.showMessage(strings.Join(msgs, ", "))
+		}
+		for _, ch := range errs {
+			go func() {
+				for err := range ch {
+					browser.showMessage(err.Error())
+				}
+			}()
+		}
+	})
+}
+
+// BucketOptions are flags of the bucket shared by the commands.
+type BucketOptions struct {
+	size      int
+	retention time.Duration
+	maxMemory byteSize
+}
+
+func (opts *BucketOptions) register(fs *flag.FlagSet) {
+	fs.IntVar(&opts.size, "buffer-size", 100, "number of the last signals kept for browsing in the interactive mode")
+	fs.DurationVar(&opts.retention, "retention", 0, "keep signals for browsing for the given time, e.g. 15m")
+	fs.Var(&opts.maxMemory, "max-memory", "max estimated memory used by signals kept for browsing, e.g. 512MB")
+}
+
+// newBucket returns the bounded bucket if retention or max memory is set,
+// the number of signals is limited then only if it's set explicitly.
+func (opts *BucketOptions) newBucket(fs *flag.FlagSet) (probe.Bucket, error) {
+	if opts.size < 1 {
+		return nil, errors.New("invalid buffer size")
+	}
+	if opts.retention <= 0 && opts.maxMemory <= 0 {
+		return probe.NewBucketFixedSize(opts.size), nil
+	}
+	return probe.NewBucketBounded(int64(opts.maxMemory), opts.retention, opts.capacity(fs)), nil
+}
+
+// capacity returns the max number of signals kept by the bucket, zero means
+// no limit.
+func (opts *BucketOptions) capacity(fs *flag.FlagSet) int {
+	if opts.retention <= 0 && opts.maxMemory <= 0 {
+		return opts.size
+	}
+	size := 0
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "buffer-size" {
+			size = opts.size
+		}
+	})
+	return size
+}
+
+// runTUI browses signals from the channel until the user quits, start is
+// called once the browser is shown.
+func runTUI(bucket probe.Bucket, filter *Query, ch chan *probe.Signal, faults *probe.FaultInjector, start func(browser *Browser)) {
+
 	s, err := tcell.NewScreen()
 
 	screen = s
@@ -65,9 +337,9 @@ func main() {
 	s.EnablePaste()
 	s.Clear()
 
-	browser := newBrowser(screen, bucket, *filterPtr, chSignal)
-	browser.refresh()
-	// go genRandomData(browser.ch)
+	browser := newBrowser(screen, bucket, filter, ch, faults)
+	browser.show()
+	start(browser)
 
 	quit := func() {
 		maybePanic := recover()
@@ -0,0 +1,234 @@
+// Package otlprobetest provides an in-process OTLP receiver for tests,
+// e.g. to check telemetry of instrumented code without a real collector:
+//
+//	srv := otlprobetest.NewServer(t)
+//	// configure the exporter with srv.GRPCEndpoint or srv.HTTPEndpoint
+//	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	defer cancel()
+//	span, err := srv.WaitForSpan(ctx, func(sp ptrace.Span) bool {
+//		return sp.Name() == "POST /orders"
+//	})
+package otlprobetest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/tomplus/otlprobe/probe"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// Server receives OTLP requests on ephemeral local ports and keeps all
+// received signals until Reset.
+type Server struct {
+	// GRPCEndpoint is the address of the gRPC receiver, e.g. 127.0.0.1:41234.
+	GRPCEndpoint string
+	// HTTPEndpoint is the URL of the OTLP/HTTP receiver, e.g. http://127.0.0.1:41235.
+	HTTPEndpoint string
+	// Probe is the underlying server, e.g. to inject faults with Probe.Faults.
+	Probe *probe.Server
+
+	mu         sync.Mutex
+	signals    []*probe.Signal
+	generation int           // incremented by Reset
+	changed    chan struct{} // closed when a signal is received or on Reset
+}
+
+// MetricPoint is a single data point with its metric and resource.
+// DataPoint is one of pmetric.NumberDataPoint, pmetric.HistogramDataPoint,
+// pmetric.ExponentialHistogramDataPoint or pmetric.SummaryDataPoint.
+type MetricPoint struct {
+	Resource  pcommon.Resource
+	Metric    pmetric.Metric
+	DataPoint any
+}
+
+// NewServer starts the receivers, they are stopped by t.Cleanup.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	grpcLis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("otlprobetest: %v", err)
+	}
+	httpLis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		grpcLis.Close()
+		t.Fatalf("otlprobetest: %v", err)
+	}
+
+	ch := make(chan *probe.Signal)
+	s := Server{
+		GRPCEndpoint: grpcLis.Addr().String(),
+		HTTPEndpoint: "http://" + httpLis.Addr().String(),
+		Probe:        probe.NewServer(0, 0, ch),
+		changed:      make(chan struct{}),
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		s.Probe.ServeGRPC(grpcLis)
+	}()
+	go func() {
+		defer wg.Done()
+		s.Probe.ServeOTLPHTTP(httpLis)
+	}()
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-ch:
+				s.add(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	t.Cleanup(func() {
+		s.Probe.Stop()
+		wg.Wait()
+		close(done)
+	})
+	return &s
+}
+
+func (s *Server) add(sig *probe.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.signals = append(s.signals, sig)
+	s.notify()
+}
+
+func (s *Server) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// Signals returns all received signals in the order of arrival.
+func (s *Server) Signals() []*probe.Signal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*probe.Signal(nil), s.signals...)
+}
+
+// Reset forgets received signals, e.g. between subtests.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.signals = nil
+	s.generation++
+	s.notify()
+}
+
+// Spans returns all received spans.
+func (s *Server) Spans() []ptrace.Span {
+	res := make([]ptrace.Span, 0)
+	for _, sig := range s.Signals() {
+		if sp, ok := probe.TraceSpan(sig); ok {
+			res = append(res, sp)
+		}
+	}
+	return res
+}
+
+// Logs returns all received log records.
+func (s *Server) Logs() []plog.LogRecord {
+	res := make([]plog.LogRecord, 0)
+	for _, sig := range s.Signals() {
+		if lr, ok := probe.LogRecord(sig); ok {
+			res = append(res, lr)
+		}
+	}
+	return res
+}
+
+// MetricPoints returns all received data points of the metric.
+func (s *Server) MetricPoints(name string) []MetricPoint {
+	res := make([]MetricPoint, 0)
+	for _, sig := range s.Signals() {
+		if mp, ok := metricPoint(sig); ok && mp.Metric.Name() == name {
+			res = append(res, mp)
+		}
+	}
+	return res
+}
+
+func metricPoint(sig *probe.Signal) (MetricPoint, bool) {
+	if !probe.HasMetric(sig) {
+		return MetricPoint{}, false
+	}
+	return MetricPoint{Resource: sig.Resource, Metric: sig.Metric, DataPoint: sig.DataPoint}, true
+}
+
+// WaitFor returns the first signal (already received or a new one) the
+// predicate accepts, or an error when the context is done.
+func (s *Server) WaitFor(ctx context.Context, predicate func(sig *probe.Signal) bool) (*probe.Signal, error) {
+	next, generation := 0, -1
+	for {
+		s.mu.Lock()
+		if generation != s.generation {
+			next, generation = 0, s.generation // reset in the meantime
+		}
+		pending := s.signals[next:]
+		next = len(s.signals)
+		changed := s.changed
+		s.mu.Unlock()
+
+		for _, sig := range pending {
+			if predicate(sig) {
+				return sig, nil
+			}
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// WaitForSpan returns the first span the predicate accepts.
+func (s *Server) WaitForSpan(ctx context.Context, predicate func(sp ptrace.Span) bool) (ptrace.Span, error) {
+	sig, err := s.WaitFor(ctx, func(sig *probe.Signal) bool {
+		sp, ok := probe.TraceSpan(sig)
+		return ok && predicate(sp)
+	})
+	if err != nil {
+		return ptrace.Span{}, fmt.Errorf("otlprobetest: no matching span: %w", err)
+	}
+	return sig.Span, nil
+}
+
+// WaitForLog returns the first log record the predicate accepts.
+func (s *Server) WaitForLog(ctx context.Context, predicate func(lr plog.LogRecord) bool) (plog.LogRecord, error) {
+	sig, err := s.WaitFor(ctx, func(sig *probe.Signal) bool {
+		lr, ok := probe.LogRecord(sig)
+		return ok && predicate(lr)
+	})
+	if err != nil {
+		return plog.LogRecord{}, fmt.Errorf("otlprobetest: no matching log record: %w", err)
+	}
+	return sig.Record, nil
+}
+
+// WaitForMetricPoint returns the first data point of the metric the
+// predicate accepts.
+func (s *Server) WaitForMetricPoint(ctx context.Context, name string, predicate func(mp MetricPoint) bool) (MetricPoint, error) {
+	sig, err := s.WaitFor(ctx, func(sig *probe.Signal) bool {
+		mp, ok := metricPoint(sig)
+		return ok && mp.Metric.Name() == name && predicate(mp)
+	})
+	if err != nil {
+		return MetricPoint{}, fmt.Errorf("otlprobetest: no matching data point of %s: %w", name, err)
+	}
+	mp, _ := metricPoint(sig)
+	return mp, nil
+}
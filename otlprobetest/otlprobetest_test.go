@@ -0,0 +1,149 @@
+package otlprobetest
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func newTestTraces(names ...string) ptrace.Traces {
+	traces := ptrace.NewTraces()
+	rs := traces.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("service.name", "checkout")
+	ss := rs.ScopeSpans().AppendEmpty()
+	for _, name := range names {
+		ss.Spans().AppendEmpty().SetName(name)
+	}
+	return traces
+}
+
+func postTraces(t *testing.T, srv *Server, traces ptrace.Traces) {
+	body, err := ptraceotlp.NewExportRequestFromTraces(traces).MarshalProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(srv.HTTPEndpoint+"/v1/traces", "application/x-protobuf", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("invalid status => %v", resp.Status)
+	}
+}
+
+func TestWaitForSpan(t *testing.T) {
+
+	srv := NewServer(t)
+	postTraces(t, srv, newTestTraces("GET /health", "POST /orders"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	span, err := srv.WaitForSpan(ctx, func(sp ptrace.Span) bool { return sp.Name() == "POST /orders" })
+	if err != nil || span.Name() != "POST /orders" {
+		t.Errorf("invalid span => %v, %v", span.Name(), err)
+	}
+	if spans := srv.Spans(); len(spans) != 2 || spans[0].Name() != "GET /health" {
+		t.Errorf("invalid spans => %v", len(spans))
+	}
+
+	// a span received while waiting
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		postTraces(t, srv, newTestTraces("DELETE /orders"))
+	}()
+	span, err = srv.WaitForSpan(ctx, func(sp ptrace.Span) bool { return sp.Name() == "DELETE /orders" })
+	if err != nil || span.Name() != "DELETE /orders" {
+		t.Errorf("invalid awaited span => %v, %v", span.Name(), err)
+	}
+
+	srv.Reset()
+	short, cancelShort := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelShort()
+	if _, err := srv.WaitForSpan(short, func(sp ptrace.Span) bool { return true }); err == nil || len(srv.Spans()) != 0 {
+		t.Errorf("it should fail after reset => %v", err)
+	}
+
+}
+
+func TestWaitForAfterReset(t *testing.T) {
+
+	srv := NewServer(t)
+	postTraces(t, srv, newTestTraces("GET /health"))
+
+	// the reset and new spans, more than before, arrive during the wait
+	reset := false
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	span, err := srv.WaitForSpan(ctx, func(sp ptrace.Span) bool {
+		if !reset {
+			reset = true
+			srv.Reset()
+			postTraces(t, srv, newTestTraces("POST /orders", "GET /orders"))
+		}
+		return sp.Name() == "POST /orders"
+	})
+	if err != nil {
+		t.Fatalf("the span after reset should be found => %v", err)
+	}
+	if span.Name() != "POST /orders" {
+		t.Errorf("invalid span after reset => %v", span.Name())
+	}
+
+}
+
+func TestLogsAndMetricPoints(t *testing.T) {
+
+	srv := NewServer(t)
+	conn, err := grpc.NewClient(srv.GRPCEndpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	logs := plog.NewLogs()
+	lr := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	lr.Body().SetStr("order created")
+	if _, err := plogotlp.NewGRPCClient(conn).Export(ctx, plogotlp.NewExportRequestFromLogs(logs)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := srv.WaitForLog(ctx, func(lr plog.LogRecord) bool { return lr.Body().Str() == "order created" }); err != nil {
+		t.Errorf("log record should be received => %v", err)
+	}
+	if got := srv.Logs(); len(got) != 1 || got[0].Body().Str() != "order created" {
+		t.Errorf("invalid logs => %v", len(got))
+	}
+
+	metrics := pmetric.NewMetrics()
+	m := metrics.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+	m.SetName("orders")
+	dps := m.SetEmptySum().DataPoints()
+	dps.AppendEmpty().SetIntValue(1)
+	dps.AppendEmpty().SetIntValue(3)
+	if _, err := pmetricotlp.NewGRPCClient(conn).Export(ctx, pmetricotlp.NewExportRequestFromMetrics(metrics)); err != nil {
+		t.Fatal(err)
+	}
+	mp, err := srv.WaitForMetricPoint(ctx, "orders", func(mp MetricPoint) bool {
+		return mp.DataPoint.(pmetric.NumberDataPoint).IntValue() == 3
+	})
+	if err != nil || mp.Metric.Type() != pmetric.MetricTypeSum {
+		t.Errorf("invalid metric point => %v, %v", mp, err)
+	}
+	if points := srv.MetricPoints("orders"); len(points) != 2 || len(srv.MetricPoints("other")) != 0 {
+		t.Errorf("invalid metric points => %v", len(points))
+	}
+
+}
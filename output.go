@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/tomplus/otlprobe/probe"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// ANSI escape codes used by colorized output.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+)
+
+var outputFormats = []string{"text", "jsonl", "otlp-json", "logfmt", "template=..."}
+
+// Output prints signals in the non-interactive mode, each signal is
+// written with a single call, so lines are never interleaved.
+type Output struct {
+	w      io.Writer
+	format string
+	tmpl   *template.Template
+	color  bool
+
+	// requests recently written by the otlp-json format, signals of
+	// concurrent requests can be interleaved
+	written     [256]uint64
+	writtenNext int
+}
+
+// newOutput creates the output for the format: text, jsonl, otlp-json,
+// logfmt or template=<text/template>.
+func newOutput(w io.Writer, format string, color bool) (*Output, error) {
+	o := Output{w: w, format: format, color: color}
+	if text, ok := strings.CutPrefix(format, "template="); ok {
+		tmpl, err := template.New("output").Funcs(template.FuncMap{"json": templateJSON}).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output template: %w", err)
+		}
+		o.format, o.tmpl = "template", tmpl
+		return &o, nil
+	}
+	switch format {
+	case "text", "jsonl", "otlp-json", "logfmt":
+		return &o, nil
+	}
+	return nil, fmt.Errorf("invalid output format %q, expected one of %s", format, strings.Join(outputFormats, ", "))
+}
+
+// write prints the i-th received signal.
+func (o *Output) write(i int, s *probe.Signal) error {
+	var buf bytes.Buffer
+	var err error
+	switch o.format {
+	case "text":
+		o.writeText(&buf, i, s)
+	case "jsonl":
+		err = o.writeJSON(&buf, i, s)
+	case "otlp-json":
+		err = o.writeOTLPJSON(&buf, s)
+	case "logfmt":
+		o.writeLogfmt(&buf, i, s)
+	case "template":
+		err = o.tmpl.Execute(&buf, newTemplateSignal(i, s))
+		if err == nil && (buf.Len() == 0 || buf.Bytes()[buf.Len()-1] != '\n') {
+			buf.WriteByte('\n')
+		}
+	}
+	if err != nil {
+		return err
+	}
+	_, err = o.w.Write(buf.Bytes())
+	return err
+}
+
+func (o *Output) writeText(buf *bytes.Buffer, i int, s *probe.Signal) {
+	prefix := fmt.Sprintf("%v: %v", i, s.Time.AsTime().String()[0:23])
+	if !o.color {
+		fmt.Fprintf(buf, "%v %v\n", prefix, s.Summary())
+		return
+	}
+	fmt.Fprintf(buf, "%s%s%s ", ansiDim, prefix, ansiReset)
+	if c := signalColor(s); c != "" {
+		fmt.Fprintf(buf, "%s%s%s\n", c, s.Summary(), ansiReset)
+		return
+	}
+	fmt.Fprintf(buf, "%s\n", s.Summary())
+}
+
+// writeJSON writes the signal as a flat JSON object, properties are keyed
+// by the section and the name, e.g. "Resource.Attributes.service.name".
+func (o *Output) writeJSON(buf *bytes.Buffer, i int, s *probe.Signal) error {
+	fmt.Fprintf(buf, `{"index":%d,`, i)
+	if err := writeSignalFields(buf, s); err != nil {
+		return err
+	}
+	buf.WriteString("}\n")
+	return nil
+}
+
+// writeSignalFields writes the fields of the flat JSON object without braces.
+func writeSignalFields(buf *bytes.Buffer, s *probe.Signal) error {
+	fmt.Fprintf(buf, `"kind":%q,"time":%q`, kindName(s.Kind), signalTime(s))
+	field := func(key string, value any) error {
+		b, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		k, _ := json.Marshal(key)
+		buf.WriteByte(',')
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(b)
+		return nil
+	}
+	if err := field("summary", s.Summary()); err != nil {
+		return err
+	}
+	for _, p := range s.Properties() {
+		for _, e := range p.Entries() {
+			value := any(e.Text)
+			if e.Typed {
+				value = e.Value.AsRaw()
+			}
+			if err := field(p.Name()+"."+e.Name, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeOTLPJSON writes the received request when its first signal is
+// written, so every line can be read by the view and replay commands.
+func (o *Output) writeOTLPJSON(buf *bytes.Buffer, s *probe.Signal) error {
+	d := s.Request
+	if d == nil || slices.Contains(o.written[:], d.ID) {
+		return nil
+	}
+	o.written[o.writtenNext] = d.ID
+	o.writtenNext = (o.writtenNext + 1) % len(o.written)
+	var b []byte
+	var err error
+	switch d.Kind {
+	case probe.LOG:
+		b, err = (&plog.JSONMarshaler{}).MarshalLogs(d.Logs)
+	case probe.METRIC:
+		b, err = (&pmetric.JSONMarshaler{}).MarshalMetrics(d.Metrics)
+	case probe.TRACE:
+		b, err = (&ptrace.JSONMarshaler{}).MarshalTraces(d.Traces)
+	}
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	buf.WriteByte('\n')
+	return nil
+}
+
+func (o *Output) writeLogfmt(buf *bytes.Buffer, i int, s *probe.Signal) {
+	pair := func(key string, value string) {
+		if buf.Len() > 0 {
+			buf.WriteByte(' ')
+		}
+		if o.color {
+			fmt.Fprintf(buf, "%s%s=%s%s", ansiDim, logfmtKey(key), ansiReset, logfmtValue(value))
+			return
+		}
+		fmt.Fprintf(buf, "%s=%s", logfmtKey(key), logfmtValue(value))
+	}
+	pair("index", strconv.Itoa(i))
+	pair("kind", kindName(s.Kind))
+	pair("time", signalTime(s))
+	pair("summary", s.Summary())
+	for _, p := range s.Properties() {
+		for _, e := range p.Entries() {
+			pair(p.Name()+"."+e.Name, e.Text)
+		}
+	}
+	buf.WriteByte('\n')
+}
+
+// logfmtKey replaces characters which are not allowed in keys, e.g.
+// spaces in "Event 0 exception".
+func logfmtKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+func logfmtValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " =\"\\\t\r\n") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// signalLevel classifies the signal for highlighting: error, warn, debug
+// (debug and trace logs), metric or empty.
+func signalLevel(s *probe.Signal) string {
+	if sp, ok := probe.TraceSpan(s); ok && sp.Status().Code() == ptrace.StatusCodeError {
+		return "error"
+	}
+	if lr, ok := probe.LogRecord(s); ok {
+		switch n := lr.SeverityNumber(); {
+		case n >= plog.SeverityNumberError:
+			return "error"
+		case n >= plog.SeverityNumberWarn:
+			return "warn"
+		case n > plog.SeverityNumberUnspecified && n < plog.SeverityNumberInfo:
+			return "debug"
+		}
+	}
+	if s.Kind == probe.METRIC {
+		return "metric"
+	}
+	return ""
+}
+
+// signalColor returns the color of errors and warnings, debug and trace
+// logs are dimmed.
+func signalColor(s *probe.Signal) string {
+	switch signalLevel(s) {
+	case "error":
+		return ansiRed
+	case "warn":
+		return ansiYellow
+	case "debug":
+		return ansiDim
+	case "metric":
+		return ansiCyan
+	}
+	return ""
+}
+
+func signalTime(s *probe.Signal) string {
+	return s.Time.AsTime().Format(time.RFC3339Nano)
+}
+
+// TemplateSignal is the data passed to the output template, e.g.
+// '{{.Time.Format "15:04:05"}} {{index .Properties "Resource.Attributes.service.name"}} {{.Summary}}'.
+type TemplateSignal struct {
+	Index      int
+	Kind       string
+	Time       time.Time
+	Summary    string
+	Properties map[string]string
+}
+
+func newTemplateSignal(i int, s *probe.Signal) TemplateSignal {
+	ts := TemplateSignal{
+		Index:      i,
+		Kind:       kindName(s.Kind),
+		Time:       s.Time.AsTime(),
+		Summary:    s.Summary(),
+		Properties: make(map[string]string),
+	}
+	for _, p := range s.Properties() {
+		for _, e := range p.Entries() {
+			ts.Properties[p.Name()+"."+e.Name] = e.Text
+		}
+	}
+	return ts
+}
+
+func templateJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// RotatingFile is a writer which renames the file to path.1, path.2, ...
+// when it exceeds maxSize, at most keep old files are kept.
+type RotatingFile struct {
+	path    string
+	maxSize int64
+	keep    int
+	file    *os.File
+	size    int64
+}
+
+func openRotatingFile(path string, maxSize int64, keep int) (*RotatingFile, error) {
+	r := RotatingFile{path: path, maxSize: maxSize, keep: keep}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file, r.size = f, fi.Size()
+	return nil
+}
+
+// rotate shifts the old files by one, the oldest one is removed.
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	os.Remove(fmt.Sprintf("%s.%d", r.path, r.keep))
+	for i := r.keep - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	if r.keep > 0 {
+		if err := os.Rename(r.path, r.path+".1"); err != nil {
+			return err
+		}
+	} else if err := os.Remove(r.path); err != nil {
+		return err
+	}
+	return r.open()
+}
+
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *RotatingFile) Close() error {
+	return r.file.Close()
+}
+
+// useColor resolves --color, auto colorizes only a terminal unless
+// NO_COLOR is set.
+func useColor(mode string, f *os.File) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		fi, err := f.Stat()
+		return err == nil && fi.Mode()&os.ModeCharDevice != 0, nil
+	}
+	return false, fmt.Errorf("invalid color mode %q, expected auto, always or never", mode)
+}
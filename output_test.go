@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tomplus/otlprobe/probe"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func newOutputSignals() []*probe.Signal {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 612345678, time.UTC)
+
+	ld := plog.NewLogs()
+	rl := ld.ResourceLogs().AppendEmpty()
+	rl.Resource().Attributes().PutStr("service.name", "cart")
+	lr := rl.ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	lr.SetTimestamp(pcommon.NewTimestampFromTime(now))
+	lr.SetSeverityNumber(plog.SeverityNumberError)
+	lr.SetSeverityText("ERROR")
+	lr.Body().SetStr("payment failed")
+	lr.Attributes().PutInt("attempt", 3)
+
+	md := pmetric.NewMetrics()
+	m := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+	m.SetName("requests")
+	sum := m.SetEmptySum()
+	sum.SetIsMonotonic(true)
+	sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	for i := 0; i < 2; i++ {
+		dp := sum.DataPoints().AppendEmpty()
+		dp.SetTimestamp(pcommon.NewTimestampFromTime(now))
+		dp.SetIntValue(int64(10 + i))
+	}
+
+	signals := make([]*probe.Signal, 0)
+	collect := func(s *probe.Signal) error {
+		signals = append(signals, s)
+		return nil
+	}
+	probe.LogSignals(ld, collect)
+	probe.MetricSignals(md, collect)
+	return signals
+}
+
+func writeOutput(t *testing.T, format string, color bool, signals []*probe.Signal) []string {
+	var buf bytes.Buffer
+	o, err := newOutput(&buf, format, color)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range signals {
+		if err := o.write(i+1, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
+
+func TestOutputText(t *testing.T) {
+
+	signals := newOutputSignals()
+	lines := writeOutput(t, "text", false, signals)
+	if len(lines) != 3 || lines[0] != "1: 2026-01-02 03:04:05.612 ERROR: payment failed" {
+		t.Errorf("invalid text output => %q", lines)
+	}
+	lines = writeOutput(t, "text", true, signals)
+	if !strings.HasPrefix(lines[0], ansiDim+"1: ") || !strings.Contains(lines[0], ansiRed+"ERROR: payment failed"+ansiReset) {
+		t.Errorf("invalid colorized output => %q", lines[0])
+	}
+
+}
+
+func TestOutputJSONLines(t *testing.T) {
+
+	lines := writeOutput(t, "jsonl", false, newOutputSignals())
+	var v map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &v); err != nil {
+		t.Fatalf("invalid JSON => %v: %v", err, lines[0])
+	}
+	if v["index"] != 1.0 || v["kind"] != "log" || v["time"] != "2026-01-02T03:04:05.612345678Z" || v["summary"] != "ERROR: payment failed" {
+		t.Errorf("invalid fields => %v", v)
+	}
+	if v["Record.Attributes.attempt"] != 3.0 || v["Resource.Attributes.service.name"] != "cart" {
+		t.Errorf("invalid properties => %v", v)
+	}
+
+}
+
+func TestOutputOTLPJSON(t *testing.T) {
+
+	// every request is written once, the metric one has two data points
+	lines := writeOutput(t, "otlp-json", false, newOutputSignals())
+	if len(lines) != 2 {
+		t.Fatalf("invalid number of lines => %d", len(lines))
+	}
+	d, err := decodeJSON([]byte(lines[1]))
+	if err != nil || d.Kind != probe.METRIC {
+		t.Fatalf("invalid request => %v, %v", d.Kind, err)
+	}
+	m := d.Metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+	if m.Sum().DataPoints().Len() != 2 || m.Sum().DataPoints().At(1).IntValue() != 11 || !isMonotonicCumulative(m) {
+		t.Errorf("the request should be written as received => %v", lines[1])
+	}
+	d, err = decodeJSON([]byte(lines[0]))
+	if err != nil || d.Kind != probe.LOG || d.Logs.LogRecordCount() != 1 {
+		t.Fatalf("invalid request => %v, %v", d.Kind, err)
+	}
+	if v, _ := d.Logs.ResourceLogs().At(0).Resource().Attributes().Get("service.name"); v.Str() != "cart" {
+		t.Errorf("resource should be kept => %v", lines[0])
+	}
+
+	// signals of interleaved requests don't repeat them
+	signals := newOutputSignals()
+	signals = append(signals, newOutputSignals()...)
+	signals = append(signals, signals[0], signals[2])
+	if lines := writeOutput(t, "otlp-json", false, signals); len(lines) != 4 {
+		t.Errorf("invalid number of interleaved lines => %d", len(lines))
+	}
+
+}
+
+func TestOutputLogfmt(t *testing.T) {
+
+	lines := writeOutput(t, "logfmt", false, newOutputSignals())
+	expected := `index=1 kind=log time=2026-01-02T03:04:05.612345678Z summary="ERROR: payment failed"`
+	if !strings.HasPrefix(lines[0], expected) || !strings.Contains(lines[0], " Record.Attributes.attempt=3") {
+		t.Errorf("invalid logfmt => %v", lines[0])
+	}
+	if logfmtKey("Event 0 exception.type") != "Event_0_exception.type" || logfmtValue("") != `""` || logfmtValue(`a"b`) != `"a\"b"` {
+		t.Errorf("invalid quoting")
+	}
+
+}
+
+func TestOutputTemplate(t *testing.T) {
+
+	format := `template={{.Index}} {{.Kind}} {{.Time.Format "15:04:05"}} {{index .Properties "Resource.Attributes.service.name"}} {{json .Summary}}`
+	lines := writeOutput(t, format, false, newOutputSignals())
+	if lines[0] != `1 log 03:04:05 cart "ERROR: payment failed"` {
+		t.Errorf("invalid template output => %q", lines[0])
+	}
+	for _, format := range []string{"yaml", "template={{.Index"} {
+		if _, err := newOutput(nil, format, false); err == nil {
+			t.Errorf("format %q should fail", format)
+		}
+	}
+
+}
+
+func TestRotatingFile(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "out.log")
+	r, err := openRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"one\n", "two\n", "three\n", "four\n", "five\n", "six\n"} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.Close()
+
+	for file, expected := range map[string]string{"": "six\n", ".1": "four\nfive\n", ".2": "three\n"} {
+		if data, err := os.ReadFile(path + file); err != nil || string(data) != expected {
+			t.Errorf("invalid content of %v => %q, %v", path+file, data, err)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); err == nil {
+		t.Errorf("only 2 rotated files should be kept")
+	}
+
+}
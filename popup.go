@@ -1,38 +1,154 @@
 package main
 
 import (
+	"encoding/hex"
 	"fmt"
 	"math"
+	"strings"
 	"unicode/utf8"
 
 	"github.com/gdamore/tcell/v2"
+	"github.com/tomplus/otlprobe/probe"
+	"go.opentelemetry.io/collector/pdata/pcommon"
 )
 
 type PopUp struct {
 	screen         tcell.Screen
-	data           []Properties
+	data           []probe.Properties
+	tree           []*treeNode
+	cursor         int
+	offset         int
 	x0, y0, x1, y1 int
 	visible        bool
 	frameStyle     tcell.Style
 	textStyle      tcell.Style
+	selectedStyle  tcell.Style
 }
 
+// treeNode is a line in the popup, nodes with children can be collapsed.
+type treeNode struct {
+	text     string
+	preview  string
+	children []*treeNode
+	expanded bool
+}
+
+type treeLine struct {
+	node  *treeNode
+	depth int
+}
+
+const previewLen = 60
+
 func newPopUp(screen tcell.Screen) *PopUp {
 	b := PopUp{
-		screen:     screen,
-		frameStyle: tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorDarkGray),
-		textStyle:  tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorDarkGray),
+		screen:        screen,
+		frameStyle:    tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorDarkGray),
+		textStyle:     tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorDarkGray),
+		selectedStyle: tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorLightGray),
 	}
 	b.center()
 	return &b
 }
 
-func (popUp *PopUp) show(data []Properties) {
+func (popUp *PopUp) show(data []probe.Properties) {
 	popUp.data = data
+	popUp.tree = newPropertiesTree(data)
+	popUp.cursor = 0
+	popUp.offset = 0
 	popUp.visible = true
 	popUp.refresh()
 }
 
+// newPropertiesTree creates a node per section, sections are expanded
+// and nested values are collapsed.
+func newPropertiesTree(data []probe.Properties) []*treeNode {
+	roots := make([]*treeNode, 0, len(data))
+	for _, prop := range data {
+		section := &treeNode{text: prop.Name(), expanded: true}
+		for _, p := range prop.Entries() {
+			if p.Typed {
+				section.children = append(section.children, newValueNode(p.Name, p.Value))
+			} else {
+				section.children = append(section.children, &treeNode{text: fmt.Sprintf("%s: %s", p.Name, p.Text)})
+			}
+		}
+		roots = append(roots, section)
+	}
+	return roots
+}
+
+func newValueNode(name string, v pcommon.Value) *treeNode {
+	node := &treeNode{text: fmt.Sprintf("%s (%s)", name, probe.TypeName(v))}
+	switch v.Type() {
+	case pcommon.ValueTypeMap:
+		v.Map().Range(func(k string, mv pcommon.Value) bool {
+			node.children = append(node.children, newValueNode(k, mv))
+			return true
+		})
+	case pcommon.ValueTypeSlice:
+		for i := 0; i < v.Slice().Len(); i++ {
+			node.children = append(node.children, newValueNode(fmt.Sprintf("[%d]", i), v.Slice().At(i)))
+		}
+	case pcommon.ValueTypeBytes:
+		data := v.Bytes().AsRaw()
+		for i := 0; i < len(data); i += 16 {
+			chunk := data[i:min(i+16, len(data))]
+			node.children = append(node.children, &treeNode{text: fmt.Sprintf("%04x  %s", i, spacedHex(chunk))})
+		}
+	default:
+		node.text = fmt.Sprintf("%s: %s (%s)", name, v.AsString(), probe.TypeName(v))
+		return node
+	}
+	node.preview = v.AsString()
+	if utf8.RuneCountInString(node.preview) > previewLen {
+		node.preview = string([]rune(node.preview)[:previewLen]) + "…"
+	}
+	return node
+}
+
+func spacedHex(data []byte) string {
+	parts := make([]string, len(data))
+	for i := range data {
+		parts[i] = hex.EncodeToString(data[i : i+1])
+	}
+	return strings.Join(parts, " ")
+}
+
+// lines returns visible (not collapsed) nodes in order.
+func (popUp *PopUp) lines() []treeLine {
+	res := make([]treeLine, 0)
+	var walk func(nodes []*treeNode, depth int)
+	walk = func(nodes []*treeNode, depth int) {
+		for _, n := range nodes {
+			res = append(res, treeLine{node: n, depth: depth})
+			if n.expanded {
+				walk(n.children, depth+1)
+			}
+		}
+	}
+	walk(popUp.tree, 0)
+	return res
+}
+
+func (line treeLine) String() string {
+	marker := ""
+	if len(line.node.children) > 0 {
+		marker = "▸ "
+		if line.node.expanded {
+			marker = "▾ "
+		}
+	}
+	text := line.node.text
+	if !line.node.expanded && line.node.preview != "" {
+		text += " " + line.node.preview
+	}
+	if line.depth == 0 {
+		return fmt.Sprintf(" %s%s", marker, text)
+	}
+	return fmt.Sprintf(" | %s%s%s", strings.Repeat("  ", line.depth-1), marker, text)
+}
+
 func (popUp *PopUp) center() {
 	w, h := screen.Size()
 	wm := int(math.Floor(float64(w) * 0.1 * 0.5))
@@ -64,6 +180,30 @@ func (popUp *PopUp) resize() {
 	}
 }
 
+// rowsOf returns number of screen rows used by the wrapped line.
+func (popUp *PopUp) rowsOf(text string) int {
+	width := max(popUp.x1-popUp.x0-1, 1)
+	return max((utf8.RuneCountInString(text)+width-1)/width, 1)
+}
+
+// scroll adjusts offset, so the line with cursor is visible.
+func (popUp *PopUp) scroll(lines []treeLine) {
+	if popUp.cursor < popUp.offset {
+		popUp.offset = popUp.cursor
+	}
+	height := popUp.y1 - popUp.y0 - 1
+	for popUp.offset < popUp.cursor {
+		rows := 0
+		for i := popUp.offset; i <= popUp.cursor; i++ {
+			rows += popUp.rowsOf(lines[i].String())
+		}
+		if rows <= height {
+			break
+		}
+		popUp.offset++
+	}
+}
+
 func (popUp *PopUp) refresh() {
 
 	if !popUp.visible {
@@ -85,34 +225,30 @@ func (popUp *PopUp) refresh() {
 		popUp.screen.SetContent(popUp.x1, popUp.y1, tcell.RuneLRCorner, nil, popUp.frameStyle)
 	}
 
-	lines := make([]string, 1)
-	for i := 0; i < len(popUp.data); i++ {
-		prop := popUp.data[i]
-		lines = append(lines, fmt.Sprintf(" + %s", prop.Name()))
-		row := prop.get()
-		for j := 0; j < len(row); j++ {
-			lines = append(lines, fmt.Sprintf(" | %s: %s", row[j][0], row[j][1]))
-		}
-		lines = append(lines, "")
-	}
+	lines := popUp.lines()
+	popUp.cursor = max(min(popUp.cursor, len(lines)-1), 0)
+	popUp.scroll(lines)
 
-	lf := false
-	for tl, tc, row := 0, 0, popUp.y0+1; row < popUp.y1; row++ {
+	for tl, tc, row := popUp.offset, 0, popUp.y0+1; row < popUp.y1; row++ {
+		text := ""
+		style := popUp.textStyle
+		if tl < len(lines) {
+			text = lines[tl].String()
+			if tl == popUp.cursor {
+				style = popUp.selectedStyle
+			}
+		}
 		for col := popUp.x0 + 1; col < popUp.x1; col++ {
 
 			r := ' '
-			if len(lines) > tl && len(lines[tl]) > tc {
+			if len(text) > tc {
 				var s int
-				r, s = utf8.DecodeRuneInString(lines[tl][tc:])
+				r, s = utf8.DecodeRuneInString(text[tc:])
 				tc += s
-			} else {
-				lf = true
 			}
-
-			popUp.screen.SetContent(col, row, r, nil, popUp.textStyle)
+			popUp.screen.SetContent(col, row, r, nil, style)
 		}
-		if lf {
-			lf = false
+		if len(text) <= tc {
 			tl++
 			tc = 0
 		}
@@ -122,10 +258,55 @@ func (popUp *PopUp) refresh() {
 
 func (popUp *PopUp) eventKey(ev *tcell.EventKey) bool {
 
-	if ev.Key() == tcell.KeyEscape {
+	lines := popUp.lines()
+	var node *treeNode
+	if popUp.cursor >= 0 && popUp.cursor < len(lines) {
+		node = lines[popUp.cursor].node
+	}
+	page := max(popUp.y1-popUp.y0-2, 1)
+
+	switch ev.Key() {
+	case tcell.KeyEscape:
 		popUp.visible = false
-		return true
+	case tcell.KeyUp:
+		popUp.cursor = max(popUp.cursor-1, 0)
+	case tcell.KeyDown:
+		popUp.cursor = min(popUp.cursor+1, len(lines)-1)
+	case tcell.KeyPgUp:
+		popUp.cursor = max(popUp.cursor-page, 0)
+	case tcell.KeyPgDn:
+		popUp.cursor = min(popUp.cursor+page, len(lines)-1)
+	case tcell.KeyHome:
+		popUp.cursor = 0
+	case tcell.KeyEnd:
+		popUp.cursor = len(lines) - 1
+	case tcell.KeyEnter:
+		if node != nil && len(node.children) > 0 {
+			node.expanded = !node.expanded
+		}
+	case tcell.KeyRight:
+		if node != nil && len(node.children) > 0 {
+			node.expanded = true
+		}
+	case tcell.KeyLeft:
+		if node != nil && node.expanded && len(node.children) > 0 {
+			node.expanded = false
+		} else {
+			// move to the parent
+			for i := popUp.cursor - 1; i >= 0; i-- {
+				if lines[i].depth < lines[popUp.cursor].depth {
+					popUp.cursor = i
+					break
+				}
+			}
+		}
+	default:
+		if ev.Rune() == ' ' && node != nil && len(node.children) > 0 {
+			node.expanded = !node.expanded
+			return true
+		}
+		return false
 	}
 
-	return false
+	return true
 }
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tomplus/otlprobe/probe"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+func TestPropertiesTree(t *testing.T) {
+
+	body := pcommon.NewValueMap()
+	body.Map().PutStr("msg", "order created")
+	items := body.Map().PutEmptySlice("items")
+	items.AppendEmpty().SetInt(1)
+	items.AppendEmpty().SetDouble(2.5)
+	body.Map().PutEmptyBytes("raw").FromRaw([]byte{0xca, 0xfe})
+
+	props := probe.NewPropsContainer("Record")
+	props.AddValue("Body", body)
+	props.AddString("SeverityText", "INFO")
+
+	// flat view keeps the JSON representation
+	attr := props.Get()
+	if attr[0][0] != "Body" || attr[0][1] != `{"items":[1,2.5],"msg":"order created","raw":"yv4="}` {
+		t.Errorf("invalid flat body => %v", attr[0])
+	}
+
+	popUp := PopUp{}
+	popUp.tree = newPropertiesTree([]probe.Properties{props})
+	lines := []string{}
+	for _, l := range popUp.lines() {
+		lines = append(lines, l.String())
+	}
+	if !reflect.DeepEqual(lines, []string{
+		" ▾ Record",
+		` | ▸ Body (map[3]) {"items":[1,2.5],"msg":"order created","raw":"yv4="}`,
+		" | SeverityText: INFO",
+	}) {
+		t.Errorf("invalid collapsed tree: %q", lines)
+	}
+
+	// expand body and nested slice
+	bodyNode := popUp.tree[0].children[0]
+	bodyNode.expanded = true
+	bodyNode.children[1].expanded = true
+	bodyNode.children[2].expanded = true
+	lines = lines[:0]
+	for _, l := range popUp.lines() {
+		lines = append(lines, l.String())
+	}
+	if !reflect.DeepEqual(lines, []string{
+		" ▾ Record",
+		" | ▾ Body (map[3])",
+		" |   msg: order created (str)",
+		" |   ▾ items (slice[2])",
+		" |     [0]: 1 (int)",
+		" |     [1]: 2.5 (double)",
+		" |   ▾ raw (bytes[2])",
+		" |     0000  ca fe",
+		" | SeverityText: INFO",
+	}) {
+		t.Errorf("invalid expanded tree: %q", lines)
+	}
+
+}
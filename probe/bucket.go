@@ -0,0 +1,282 @@
+package probe
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bucket keeps received signals for browsing, Get(0) is the latest one.
+type Bucket interface {
+	Append(signal *Signal)
+	Clear()
+	Len() int
+	Get(i int) (bool, *Signal)
+	Counter() int
+	Evicted() Evictions
+}
+
+// Evictions counts signals removed from the bucket to make room for new ones,
+// by the reason of removal.
+type Evictions struct {
+	capacity int
+	memory   int
+	age      int
+}
+
+func (e Evictions) Total() int {
+	return e.capacity + e.memory + e.age
+}
+
+// String returns non-zero counters, e.g. "12 capacity, 3 age".
+func (e Evictions) String() string {
+	parts := make([]string, 0, 3)
+	for _, c := range []struct {
+		name  string
+		count int
+	}{{"capacity", e.capacity}, {"memory", e.memory}, {"age", e.age}} {
+		if c.count > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", c.count, c.name))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// BucketFixedSize keeps the last size signals in a ring buffer. It's safe for
+// concurrent use, signals are appended by the receiver while the browser
+// reads them.
+type BucketFixedSize struct {
+	mu   sync.RWMutex
+	size int
+	data []*Signal
+	head int // position of the next signal
+	n    int
+	cnt  int
+	ev   Evictions
+}
+
+func NewBucketFixedSize(size int) *BucketFixedSize {
+	b := BucketFixedSize{size: max(size, 1)}
+	b.data = make([]*Signal, b.size)
+	return &b
+}
+
+func (b *BucketFixedSize) Append(signal *Signal) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.data[b.head] = signal
+	b.head = (b.head + 1) % b.size
+	if b.n < b.size {
+		b.n++
+	} else {
+		b.ev.capacity++
+	}
+	b.cnt++
+}
+
+func (b *BucketFixedSize) Clear() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	clear(b.data)
+	b.head = 0
+	b.n = 0
+	b.ev = Evictions{}
+}
+
+func (b *BucketFixedSize) Len() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.n
+}
+
+// Get returns i-th signal counting from the newest one.
+func (b *BucketFixedSize) Get(i int) (bool, *Signal) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if i < 0 || i >= b.n {
+		return false, nil
+	}
+	return true, b.data[(b.head-1-i+b.size)%b.size]
+}
+
+func (b *BucketFixedSize) Counter() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.cnt
+}
+
+func (b *BucketFixedSize) Evicted() Evictions {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.ev
+}
+
+// BucketBounded keeps signals until they exceed the estimated memory budget,
+// the retention period or the capacity (zero means no limit). The oldest
+// signals are evicted first. It's safe for concurrent use.
+type BucketBounded struct {
+	mu        sync.Mutex
+	maxBytes  int64
+	retention time.Duration
+	size      int
+	now       func() time.Time
+
+	data  []boundedEntry
+	start int // index of the oldest signal in data
+	bytes int64
+	cnt   int
+	ev    Evictions
+}
+
+type boundedEntry struct {
+	signal   *Signal
+	bytes    int64
+	received time.Time
+}
+
+func NewBucketBounded(maxBytes int64, retention time.Duration, size int) *BucketBounded {
+	b := BucketBounded{maxBytes: maxBytes, retention: retention, size: size, now: time.Now}
+	b.data = make([]boundedEntry, 0)
+	return &b
+}
+
+// Append stores a copy of the signal, so evicted signals release their memory
+// even if other signals of the same request are kept.
+func (b *BucketBounded) Append(signal *Signal) {
+	signal, protoSize := detach(signal)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e := boundedEntry{signal: signal, bytes: estimateSize(protoSize), received: b.now()}
+	b.data = append(b.data, e)
+	b.bytes += e.bytes
+	b.cnt++
+
+	b.expire()
+	for b.size > 0 && b.count() > b.size {
+		b.evictOldest()
+		b.ev.capacity++
+	}
+	// the newest signal is kept even if it exceeds the budget itself
+	for b.maxBytes > 0 && b.bytes > b.maxBytes && b.count() > 1 {
+		b.evictOldest()
+		b.ev.memory++
+	}
+}
+
+// expire evicts signals older than the retention period.
+func (b *BucketBounded) expire() {
+	if b.retention <= 0 {
+		return
+	}
+	deadline := b.now().Add(-b.retention)
+	for b.count() > 0 && b.data[b.start].received.Before(deadline) {
+		b.evictOldest()
+		b.ev.age++
+	}
+}
+
+func (b *BucketBounded) count() int {
+	return len(b.data) - b.start
+}
+
+func (b *BucketBounded) evictOldest() {
+	b.bytes -= b.data[b.start].bytes
+	b.data[b.start] = boundedEntry{}
+	b.start++
+	// compact the slice when the evicted part is larger than the rest
+	if b.start > len(b.data)/2 {
+		n := copy(b.data, b.data[b.start:])
+		clear(b.data[n:])
+		b.data = b.data[:n]
+		b.start = 0
+	}
+}
+
+func (b *BucketBounded) Clear() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.data = make([]boundedEntry, 0)
+	b.start = 0
+	b.bytes = 0
+	b.ev = Evictions{}
+}
+
+// Len evicts expired signals, so they disappear even if nothing new comes.
+func (b *BucketBounded) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.expire()
+	return b.count()
+}
+
+// Get returns i-th signal counting from the newest one.
+func (b *BucketBounded) Get(i int) (bool, *Signal) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if i < 0 || i >= b.count() {
+		return false, nil
+	}
+	return true, b.data[len(b.data)-1-i].signal
+}
+
+func (b *BucketBounded) Counter() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.cnt
+}
+
+func (b *BucketBounded) Evicted() Evictions {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.ev
+}
+
+// memory returns the estimated size of the stored signals.
+func (b *BucketBounded) memory() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.bytes
+}
+
+// signalOverhead is a rough size of the signal, the pdata containers of its
+// copy and the sections of properties.
+const signalOverhead = 2048
+
+// sizeFactor relates the protobuf size of the record to the memory used by
+// its pdata structures together with the summary and properties built on
+// first use, it was measured up to 6 for histograms with many buckets.
+const sizeFactor = 7
+
+// detach copies the record of the signal out of the request, so an evicted
+// signal doesn't keep the whole request alive, and returns the protobuf size
+// of the copy. Signals without the record (e.g. in tests) are kept.
+func detach(s *Signal) (*Signal, int64) {
+	_, span := TraceSpan(s)
+	_, record := LogRecord(s)
+	if !span && !record && !HasMetric(s) {
+		return s, 0
+	}
+	d := s.singleRequest()
+	var c *Signal
+	d.Signals(func(ds *Signal) error {
+		c = ds
+		return nil
+	})
+	c.Lint = s.Lint
+	return c, int64(d.Size())
+}
+
+// estimateSize estimates memory used by the detached signal of the protobuf
+// size, its resource and scope are counted as they aren't shared with other
+// signals anymore.
+func estimateSize(protoSize int64) int64 {
+	return signalOverhead + sizeFactor*protoSize
+}
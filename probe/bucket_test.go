@@ -0,0 +1,259 @@
+package probe
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestBucketFixedSizeAppend(t *testing.T) {
+
+	b := NewBucketFixedSize(5)
+
+	// append
+	b.Append(&Signal{summary: "test1"})
+	if b.Len() != 1 {
+		t.Errorf("invalid len() => %d", b.Len())
+	}
+	ok, v := b.Get(0)
+	if v.summary != "test1" || !ok {
+		t.Errorf("invalid get() => %v, %v", ok, v)
+	}
+	ok, v = b.Get(1)
+	if ok {
+		t.Errorf("invalid get() => %v, %v", ok, v)
+	}
+
+	// clear
+	b.Clear()
+	if b.Len() != 0 {
+		t.Errorf("invalid len() => %d", b.Len())
+	}
+
+	// fixed size & get
+	for i := 0; i < 10; i++ {
+		s := Signal{summary: fmt.Sprintf("something %d", i)}
+		b.Append(&s)
+	}
+	if b.Len() != 5 {
+		t.Errorf("invalid len() => %d", b.Len())
+	}
+	ok, v = b.Get(0)
+	if v.summary != "something 9" || !ok {
+		t.Errorf("invalid get() => %v, %v", ok, v)
+	}
+	ok, v = b.Get(4)
+	if v.summary != "something 5" || !ok {
+		t.Errorf("invalid get() => %v, %v", ok, v)
+	}
+	b.Clear()
+	if b.Evicted().Total() != 0 {
+		t.Errorf("clear should reset evictions => %v", b.Evicted())
+	}
+
+}
+
+func TestBucketFixedSizeConcurrent(t *testing.T) {
+
+	b := NewBucketFixedSize(100)
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				b.Append(&Signal{summary: fmt.Sprintf("signal %d", i)})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				if ok, v := b.Get(i % 100); ok && v == nil {
+					t.Errorf("invalid get() => %v, %v", ok, v)
+				}
+				b.Len()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if b.Len() != 100 || b.Counter() != 4000 {
+		t.Errorf("invalid len(), counter() => %d, %d", b.Len(), b.Counter())
+	}
+	if ok, _ := b.Get(-1); ok {
+		t.Errorf("negative index should not be found")
+	}
+	if ok, _ := b.Get(100); ok {
+		t.Errorf("index out of range should not be found")
+	}
+
+}
+
+func BenchmarkBucketFixedSizeAppend(b *testing.B) {
+
+	bucket := NewBucketFixedSize(10000)
+	s := &Signal{summary: "signal"}
+	for b.Loop() {
+		bucket.Append(s)
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "signals/s")
+
+}
+
+func BenchmarkBucketFixedSizeAppendWithReaders(b *testing.B) {
+
+	bucket := NewBucketFixedSize(10000)
+	s := &Signal{summary: "signal"}
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				for i := 0; i < 100; i++ {
+					bucket.Get(i)
+				}
+			}
+		}
+	}()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			bucket.Append(s)
+		}
+	})
+	close(done)
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "signals/s")
+
+}
+
+func TestBucketBounded(t *testing.T) {
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewBucketBounded(0, 15*time.Minute, 0)
+	b.now = func() time.Time { return now }
+
+	// age
+	for i := 0; i < 10; i++ {
+		b.Append(&Signal{summary: fmt.Sprintf("something %d", i)})
+		now = now.Add(time.Minute)
+	}
+	now = now.Add(10 * time.Minute)
+	if b.Len() != 5 || b.Evicted().age != 5 {
+		t.Errorf("invalid len(), evicted() => %d, %v", b.Len(), b.Evicted())
+	}
+	if ok, v := b.Get(4); !ok || v.summary != "something 5" {
+		t.Errorf("invalid get() => %v, %v", ok, v)
+	}
+	now = now.Add(time.Hour)
+	if b.Len() != 0 || b.Counter() != 10 {
+		t.Errorf("invalid len(), counter() => %d, %d", b.Len(), b.Counter())
+	}
+
+	// memory
+	b = NewBucketBounded(10*estimateSize(0), 0, 0)
+	for i := 0; i < 25; i++ {
+		b.Append(&Signal{summary: "s"})
+	}
+	if b.Len() != 10 || b.memory() != 10*estimateSize(0) || b.Evicted().memory != 15 {
+		t.Errorf("invalid len(), memory(), evicted() => %d, %d, %v", b.Len(), b.memory(), b.Evicted())
+	}
+	ld := plog.NewLogs()
+	ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr(strings.Repeat("x", 100000))
+	LogSignals(ld, func(s *Signal) error {
+		b.Append(s)
+		return nil
+	})
+	if ok, v := b.Get(0); b.Len() != 1 || !ok || v.Record.Body().Str() != strings.Repeat("x", 100000) {
+		t.Errorf("the newest signal should be kept => %d", b.Len())
+	}
+	b.Clear()
+	if b.Len() != 0 || b.memory() != 0 || b.Evicted().Total() != 0 {
+		t.Errorf("clear should reset the bucket => %d, %d, %v", b.Len(), b.memory(), b.Evicted())
+	}
+
+	// capacity
+	b = NewBucketBounded(0, 0, 3)
+	for i := 0; i < 5; i++ {
+		b.Append(&Signal{summary: fmt.Sprintf("something %d", i)})
+	}
+	if ok, v := b.Get(2); b.Len() != 3 || !ok || v.summary != "something 2" {
+		t.Errorf("invalid get() => %v, %v", ok, v)
+	}
+	if ev := b.Evicted(); ev.String() != "2 capacity" || ev.Total() != 2 {
+		t.Errorf("invalid evicted() => %v", ev)
+	}
+
+}
+
+func TestEstimateSize(t *testing.T) {
+
+	ld := plog.NewLogs()
+	ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr("payment delayed")
+	var s *Signal
+	LogSignals(ld, func(ls *Signal) error {
+		s = ls
+		return nil
+	})
+	base := estimateSize(0)
+	s.Record.Attributes().PutStr("payload", strings.Repeat("x", 1000))
+	c, protoSize := detach(s)
+	if size := estimateSize(protoSize); size < base+1000 {
+		t.Errorf("invalid estimateSize() => %d", size)
+	}
+	if c == s || c.Record.Body().Str() != "payment delayed" {
+		t.Errorf("signal should be copied => %v", c.Summary())
+	}
+
+}
+
+// newTestHistograms returns a request with histogram data points of many buckets.
+func newTestHistograms(points int, buckets int) pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	rm := md.ResourceMetrics().AppendEmpty()
+	rm.Resource().Attributes().PutStr("service.name", "checkout")
+	h := rm.ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+	h.SetName("http.server.request.duration")
+	dps := h.SetEmptyHistogram().DataPoints()
+	for i := 0; i < points; i++ {
+		dp := dps.AppendEmpty()
+		dp.Attributes().PutInt("point", int64(i))
+		for j := 0; j < buckets; j++ {
+			dp.BucketCounts().Append(uint64(j))
+			dp.ExplicitBounds().Append(float64(j))
+		}
+	}
+	return md
+}
+
+func TestBucketBoundedMemory(t *testing.T) {
+
+	const maxBytes = 1 << 20
+	b := NewBucketBounded(maxBytes, 0, 0)
+	for i := 0; i < 10; i++ {
+		MetricSignals(newTestHistograms(20, 1000), func(s *Signal) error {
+			b.Append(s)
+			return nil
+		})
+	}
+
+	// a histogram of 1000 buckets is estimated by its protobuf size, not
+	// as a small signal, so only some of them fit the limit
+	_, c := b.Get(0)
+	_, protoSize := detach(c)
+	if protoSize < 16000 {
+		t.Errorf("invalid protobuf size of the histogram => %d", protoSize)
+	}
+	if b.memory() > maxBytes || b.memory() != int64(b.Len())*estimateSize(protoSize) {
+		t.Errorf("invalid accounted memory => %d of %d bytes (%d signals)", b.memory(), maxBytes, b.Len())
+	}
+	if b.Len() < 5 || b.Len()+b.Evicted().memory != 200 || b.Evicted().Total() != b.Evicted().memory {
+		t.Errorf("invalid evictions => %d kept, %v", b.Len(), b.Evicted())
+	}
+
+}
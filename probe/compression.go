@@ -0,0 +1,136 @@
+package probe
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc/encoding"
+	_ "google.golang.org/grpc/encoding/gzip" // registers gzip compressor for gRPC
+)
+
+// zstdMaxWindow limits memory allocated for the window declared by the
+// frame, 8 MiB is enough for all levels without long distance matching.
+const zstdMaxWindow = 8 << 20
+
+var errPayloadTooLarge = errors.New("payload too large")
+
+// snappyFramedMagic is the stream identifier chunk which starts
+// every snappy stream in the framing format.
+var snappyFramedMagic = []byte("\xff\x06\x00\x00sNaPpY")
+
+func init() {
+	encoding.RegisterCompressor(zstdCompressor{})
+}
+
+// zstdCompressor implements gRPC compressor for zstd, the same one
+// which is used by the collector's exporters.
+type zstdCompressor struct{}
+
+func (zstdCompressor) Name() string {
+	return "zstd"
+}
+
+func (zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+}
+
+// Decompress returns a streaming reader, so gRPC stops reading when the
+// uncompressed message exceeds the max receive size.
+func (zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	d, err := newZstdReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &zstdReader{d: d}, nil
+}
+
+func newZstdReader(r io.Reader) (*zstd.Decoder, error) {
+	return zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxWindow(zstdMaxWindow))
+}
+
+// zstdReader releases the decoder when the stream ends.
+type zstdReader struct {
+	d *zstd.Decoder
+}
+
+func (r *zstdReader) Read(p []byte) (int, error) {
+	n, err := r.d.Read(p)
+	if err != nil {
+		r.d.Close()
+	}
+	return n, err
+}
+
+// decompressBody returns uncompressed payload for given Content-Encoding.
+// It supports encodings which are supported by the collector's confighttp.
+// The limit (if positive) applies to the uncompressed payload.
+func decompressBody(contentEncoding string, body io.Reader, limit int64) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(contentEncoding)) {
+	case "", "identity":
+		return readAllLimited(body, limit)
+	case "gzip", "x-gzip":
+		r, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return readAllLimited(r, limit)
+	case "deflate", "zlib":
+		r, err := zlib.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return readAllLimited(r, limit)
+	case "zstd":
+		r, err := newZstdReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return readAllLimited(r, limit)
+	case "snappy":
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		// older exporters send framed format with "snappy" encoding
+		if bytes.HasPrefix(data, snappyFramedMagic) {
+			return readAllLimited(snappy.NewReader(bytes.NewReader(data)), limit)
+		}
+		n, err := snappy.DecodedLen(data)
+		if err != nil {
+			return nil, err
+		}
+		if limit > 0 && int64(n) > limit {
+			return nil, errPayloadTooLarge
+		}
+		return snappy.Decode(nil, data)
+	case "x-snappy-framed":
+		return readAllLimited(snappy.NewReader(body), limit)
+	}
+	return nil, fmt.Errorf("unsupported Content-Encoding: %s", contentEncoding)
+}
+
+// readAllLimited works like io.ReadAll but fails with errPayloadTooLarge
+// if there are more than limit bytes to read.
+func readAllLimited(r io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, errPayloadTooLarge
+	}
+	return data, nil
+}
@@ -0,0 +1,95 @@
+package probe
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestDecompressBody(t *testing.T) {
+
+	payload := bytes.Repeat([]byte("otlp payload "), 100)
+
+	var gz, zl, zs, sf bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write(payload)
+	w.Close()
+	wz := zlib.NewWriter(&zl)
+	wz.Write(payload)
+	wz.Close()
+	ws, _ := zstd.NewWriter(&zs)
+	ws.Write(payload)
+	ws.Close()
+	wsf := snappy.NewBufferedWriter(&sf)
+	wsf.Write(payload)
+	wsf.Close()
+
+	tests := []struct {
+		encoding string
+		body     []byte
+	}{
+		{"", payload},
+		{"identity", payload},
+		{"gzip", gz.Bytes()},
+		{"deflate", zl.Bytes()},
+		{"zstd", zs.Bytes()},
+		{"snappy", snappy.Encode(nil, payload)},
+		{"snappy", sf.Bytes()},
+		{"x-snappy-framed", sf.Bytes()},
+	}
+	for _, tc := range tests {
+		res, err := decompressBody(tc.encoding, bytes.NewReader(tc.body), 0)
+		if err != nil || !bytes.Equal(res, payload) {
+			t.Errorf("invalid decompressBody(%q) => %v", tc.encoding, err)
+		}
+	}
+
+	if _, err := decompressBody("br", bytes.NewReader(payload), 0); err == nil {
+		t.Errorf("unsupported encoding should fail")
+	}
+	if _, err := decompressBody("gzip", bytes.NewReader(payload), 0); err == nil {
+		t.Errorf("corrupted body should fail")
+	}
+
+	// limit applies to uncompressed payload
+	for _, tc := range tests {
+		_, err := decompressBody(tc.encoding, bytes.NewReader(tc.body), int64(len(payload)-1))
+		if err != errPayloadTooLarge {
+			t.Errorf("invalid decompressBody(%q) with limit => %v", tc.encoding, err)
+		}
+		res, err := decompressBody(tc.encoding, bytes.NewReader(tc.body), int64(len(payload)))
+		if err != nil || !bytes.Equal(res, payload) {
+			t.Errorf("invalid decompressBody(%q) with limit => %v", tc.encoding, err)
+		}
+	}
+
+}
+
+func TestZstdCompressor(t *testing.T) {
+
+	payload := bytes.Repeat([]byte("grpc payload "), 100)
+
+	var buf bytes.Buffer
+	c := zstdCompressor{}
+	w, err := c.Compress(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(payload)
+	w.Close()
+
+	r, err := c.Decompress(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, _ := io.ReadAll(r)
+	if !bytes.Equal(res, payload) {
+		t.Errorf("invalid round trip => %d bytes", len(res))
+	}
+
+}
@@ -0,0 +1,175 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// FaultConfig describes how a simulated misbehaving backend responds
+// to export requests of one signal type.
+type FaultConfig struct {
+	ErrorRate  float64
+	ErrorCode  codes.Code
+	RetryAfter time.Duration
+	Latency    time.Duration
+	RejectRate float64
+}
+
+func newFaultConfig() FaultConfig {
+	return FaultConfig{ErrorCode: codes.Unavailable, RetryAfter: time.Second}
+}
+
+func (c FaultConfig) active() bool {
+	return c.ErrorRate > 0 || c.Latency > 0 || c.RejectRate > 0
+}
+
+// FaultInjector keeps fault configuration per signal type, it can be
+// changed at runtime (e.g. from the TUI) while requests are served.
+type FaultInjector struct {
+	mu      sync.RWMutex
+	configs [3]FaultConfig
+}
+
+func NewFaultInjector() *FaultInjector {
+	f := FaultInjector{}
+	for i := range f.configs {
+		f.configs[i] = newFaultConfig()
+	}
+	return &f
+}
+
+func (f *FaultInjector) Get(kind KindSignal) FaultConfig {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.configs[kind]
+}
+
+func (f *FaultInjector) Set(kind KindSignal, cfg FaultConfig) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.configs[kind] = cfg
+}
+
+func (f *FaultInjector) Active() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, c := range f.configs {
+		if c.active() {
+			return true
+		}
+	}
+	return false
+}
+
+// inject delays the request and returns an error if the request
+// should fail according to the configuration.
+func (c FaultConfig) inject(ctx context.Context) error {
+	if c.Latency > 0 {
+		select {
+		case <-time.After(c.Latency):
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+	if c.ErrorRate > 0 && rand.Float64() < c.ErrorRate {
+		st := status.New(c.ErrorCode, "failure injected by otlprobe")
+		if c.RetryAfter > 0 {
+			if std, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(c.RetryAfter)}); err == nil {
+				st = std
+			}
+		}
+		return st.Err()
+	}
+	return nil
+}
+
+// rejected returns how many of n items should be reported as rejected.
+func (c FaultConfig) rejected(n int) int64 {
+	return int64(float64(n)*c.RejectRate + 0.5)
+}
+
+const rejectedMessage = "rejected by otlprobe fault injection"
+
+var FaultSignals = map[string][]KindSignal{
+	"all":     {LOG, METRIC, TRACE},
+	"logs":    {LOG},
+	"metrics": {METRIC},
+	"traces":  {TRACE},
+}
+
+var faultErrorCodes = map[string]codes.Code{
+	"unavailable":        codes.Unavailable,
+	"resource-exhausted": codes.ResourceExhausted,
+}
+
+// ParseFaultSpec applies specification in form of [signal:]key=value,...
+// e.g. "traces:error-rate=0.2,latency=100ms" to the injector.
+func (f *FaultInjector) ParseFaultSpec(spec string) error {
+	kinds := FaultSignals["all"]
+	if signal, rest, found := strings.Cut(spec, ":"); found && !strings.Contains(signal, "=") {
+		var ok bool
+		kinds, ok = FaultSignals[signal]
+		if !ok {
+			return fmt.Errorf("unknown signal %q", signal)
+		}
+		spec = rest
+	}
+	for _, kind := range kinds {
+		cfg := f.Get(kind)
+		for _, kv := range strings.Split(spec, ",") {
+			key, value, _ := strings.Cut(strings.TrimSpace(kv), "=")
+			var err error
+			switch key {
+			case "error-rate":
+				cfg.ErrorRate, err = parseRate(value)
+			case "reject-rate":
+				cfg.RejectRate, err = parseRate(value)
+			case "latency":
+				cfg.Latency, err = time.ParseDuration(value)
+			case "retry-after":
+				cfg.RetryAfter, err = time.ParseDuration(value)
+			case "error-code":
+				code, ok := faultErrorCodes[value]
+				if !ok {
+					err = fmt.Errorf("unknown error code %q", value)
+				}
+				cfg.ErrorCode = code
+			default:
+				err = fmt.Errorf("unknown key %q", key)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		f.Set(kind, cfg)
+	}
+	return nil
+}
+
+// parseRate accepts fraction (0.25) or percentage (25%).
+func parseRate(s string) (float64, error) {
+	div := 1.0
+	if strings.HasSuffix(s, "%") {
+		s = strings.TrimSuffix(s, "%")
+		div = 100
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	v /= div
+	if v < 0 || v > 1 {
+		return 0, fmt.Errorf("rate out of range: %v", s)
+	}
+	return v, nil
+}
@@ -0,0 +1,100 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseFaultSpec(t *testing.T) {
+
+	f := NewFaultInjector()
+	if f.Active() {
+		t.Errorf("new injector should be inactive")
+	}
+
+	if err := f.ParseFaultSpec("latency=100ms"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.ParseFaultSpec("traces:error-rate=20%,error-code=resource-exhausted,retry-after=5s,reject-rate=0.5"); err != nil {
+		t.Fatal(err)
+	}
+	tr := f.Get(TRACE)
+	if tr.ErrorRate != 0.2 || tr.ErrorCode != codes.ResourceExhausted || tr.RetryAfter != 5*time.Second ||
+		tr.RejectRate != 0.5 || tr.Latency != 100*time.Millisecond {
+		t.Errorf("invalid traces config => %+v", tr)
+	}
+	lg := f.Get(LOG)
+	if lg.ErrorRate != 0 || lg.ErrorCode != codes.Unavailable || lg.Latency != 100*time.Millisecond {
+		t.Errorf("invalid logs config => %+v", lg)
+	}
+	if !f.Active() {
+		t.Errorf("injector should be active")
+	}
+
+	for _, spec := range []string{"spans:latency=1s", "error-rate=2", "latency=fast", "error-code=oops", "color=red"} {
+		if err := f.ParseFaultSpec(spec); err == nil {
+			t.Errorf("invalid spec %q should fail", spec)
+		}
+	}
+
+}
+
+func TestFaultInjection(t *testing.T) {
+
+	ch := make(chan *Signal, 10)
+	server := NewServer(0, 0, ch)
+
+	// error with retry info
+	server.Faults.Set(TRACE, FaultConfig{ErrorRate: 1, ErrorCode: codes.Unavailable, RetryAfter: 2 * time.Second})
+	_, err := traceServer{server: server}.Export(context.Background(), newTestTraces())
+	if status.Code(err) != codes.Unavailable || len(status.Convert(err).Details()) != 1 {
+		t.Errorf("invalid Export() => %v", err)
+	}
+	if len(ch) != 0 {
+		t.Errorf("failed request should not be processed")
+	}
+
+	pb, _ := newTestTraces().MarshalProto()
+	req := httptest.NewRequest(http.MethodPost, "/v1/traces", bytes.NewReader(pb))
+	req.Header.Set("Content-Type", ContentTypeProto)
+	rec := httptest.NewRecorder()
+	server.httpTraceHandler(rec, req)
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "2" {
+		t.Errorf("invalid response => %d, %v", rec.Code, rec.Header())
+	}
+
+	server.Faults.Set(TRACE, FaultConfig{ErrorRate: 1, ErrorCode: codes.ResourceExhausted})
+	rec = httptest.NewRecorder()
+	server.httpTraceHandler(rec, httptest.NewRequest(http.MethodPost, "/v1/traces", bytes.NewReader(pb)))
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "" {
+		t.Errorf("invalid response => %d, %v", rec.Code, rec.Header())
+	}
+
+	// partial success
+	server.Faults.Set(TRACE, FaultConfig{RejectRate: 1})
+	resp, err := traceServer{server: server}.Export(context.Background(), newTestTraces())
+	if err != nil || resp.PartialSuccess().RejectedSpans() != 1 || resp.PartialSuccess().ErrorMessage() == "" {
+		t.Errorf("invalid Export() => %v, %v", resp.PartialSuccess(), err)
+	}
+	if len(ch) != 1 {
+		t.Errorf("partially rejected request should be processed")
+	}
+
+	// latency
+	server.Faults.Set(TRACE, FaultConfig{Latency: time.Second})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = traceServer{server: server}.Export(ctx, ptraceotlp.NewExportRequest())
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("invalid Export() => %v", err)
+	}
+
+}
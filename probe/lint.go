@@ -0,0 +1,346 @@
+package probe
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// semconvRegistry is a subset of the OpenTelemetry semantic-conventions
+// registry, every entry knows the version it was added or deprecated in, so
+// one file covers all bundled versions.
+//
+//go:embed semconv.json
+var semconvRegistry []byte
+
+// Rules of lint violations.
+const (
+	LintUnknown    = "unknown"
+	LintDeprecated = "deprecated"
+	LintType       = "type"
+	LintMissing    = "missing"
+	LintUnit       = "unit"
+)
+
+// Violation is a difference between the signal and the semantic conventions.
+type Violation struct {
+	Rule    string
+	Key     string // attribute or metric name
+	Message string
+}
+
+func (v Violation) String() string {
+	return v.Message
+}
+
+type semconvEntry struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Unit        string `json:"unit"`
+	Template    bool   `json:"template"`
+	Since       string `json:"since"`
+	Deprecated  string `json:"deprecated"`
+	Replacement string `json:"replacement"`
+}
+
+type semconvFile struct {
+	Versions   []string            `json:"versions"`
+	Required   map[string][]string `json:"required"`
+	Attributes []semconvEntry      `json:"attributes"`
+	Metrics    []semconvEntry      `json:"metrics"`
+}
+
+// Linter checks signals against the semantic conventions of the version.
+// It's safe for concurrent use.
+type Linter struct {
+	version    string
+	attributes map[string]semconvEntry
+	templates  []semconvEntry
+	metrics    map[string]semconvEntry
+	namespaces map[string]bool
+	required   []string
+}
+
+// nonUCUMUnits are common spellings of units with the UCUM code to use instead.
+var nonUCUMUnits = map[string]string{
+	"second": "s", "seconds": "s", "sec": "s", "secs": "s",
+	"millisecond": "ms", "milliseconds": "ms", "millis": "ms", "msec": "ms",
+	"microsecond": "us", "microseconds": "us", "μs": "us",
+	"nanosecond": "ns", "nanoseconds": "ns", "nanos": "ns",
+	"minute": "min", "minutes": "min", "hour": "h", "hours": "h",
+	"byte": "By", "bytes": "By", "B": "By",
+	"kilobytes": "kBy", "KB": "kBy", "kB": "kBy", "megabytes": "MBy", "MB": "MBy", "gigabytes": "GBy", "GB": "GBy",
+	"KiB": "KiBy", "MiB": "MiBy", "GiB": "GiBy",
+	"percent": "%", "ratio": "1",
+}
+
+// ucumUnits are UCUM codes which look like words, other words need braces,
+// e.g. {request}.
+var ucumUnits = map[string]bool{
+	"min": true, "By": true, "kBy": true, "MBy": true, "GBy": true, "TBy": true,
+	"KiBy": true, "MiBy": true, "GiBy": true, "TiBy": true, "bit": true, "Hz": true, "Cel": true,
+}
+
+// SemconvVersions returns the bundled versions, the latest is the last one.
+func SemconvVersions() []string {
+	var f semconvFile
+	if err := json.Unmarshal(semconvRegistry, &f); err != nil {
+		panic(err)
+	}
+	return f.Versions
+}
+
+// NewLinter loads the registry of the version, empty version selects the
+// latest one.
+func NewLinter(version string) (*Linter, error) {
+	var f semconvFile
+	if err := json.Unmarshal(semconvRegistry, &f); err != nil {
+		return nil, err
+	}
+	if version == "" {
+		version = f.Versions[len(f.Versions)-1]
+	}
+	found := false
+	for _, v := range f.Versions {
+		found = found || v == version
+	}
+	if !found {
+		return nil, fmt.Errorf("unsupported semconv version %q, expected one of %s", version, strings.Join(f.Versions, ", "))
+	}
+
+	l := Linter{
+		version:    version,
+		attributes: make(map[string]semconvEntry),
+		metrics:    make(map[string]semconvEntry),
+		namespaces: make(map[string]bool),
+		required:   f.Required["resource"],
+	}
+	for _, e := range f.Attributes {
+		if ns, _, ok := strings.Cut(e.Name, "."); ok {
+			l.namespaces[ns] = true
+		}
+		if !availableIn(e, version) {
+			continue
+		}
+		if e.Template {
+			l.templates = append(l.templates, e)
+		} else {
+			l.attributes[e.Name] = e
+		}
+	}
+	for _, e := range f.Metrics {
+		if availableIn(e, version) {
+			l.metrics[e.Name] = e
+		}
+	}
+	return &l, nil
+}
+
+// Version returns the version of the semantic conventions.
+func (l *Linter) Version() string {
+	return l.version
+}
+
+func availableIn(e semconvEntry, version string) bool {
+	return e.Since == "" || compareVersions(e.Since, version) <= 0
+}
+
+func deprecatedIn(e semconvEntry, version string) bool {
+	return e.Deprecated != "" && compareVersions(e.Deprecated, version) <= 0
+}
+
+// compareVersions compares versions like 1.26.0 part by part.
+func compareVersions(a string, b string) int {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var na, nb int
+		if i < len(pa) {
+			na, _ = strconv.Atoi(pa[i])
+		}
+		if i < len(pb) {
+			nb, _ = strconv.Atoi(pb[i])
+		}
+		if na != nb {
+			return na - nb
+		}
+	}
+	return 0
+}
+
+// Check returns violations of the signal: attributes of the resource and
+// the record (span with its events, log record or data point) and the
+// metric name and unit.
+func (l *Linter) Check(s *Signal) []Violation {
+	res := make([]Violation, 0)
+	if s.Resource != (pcommon.Resource{}) {
+		for _, key := range l.required {
+			if _, ok := s.Resource.Attributes().Get(key); !ok {
+				res = append(res, Violation{Rule: LintMissing, Key: key, Message: fmt.Sprintf("resource attribute %s is missing", key)})
+			}
+		}
+		res = l.checkAttributes(res, "resource", s.Resource.Attributes())
+	}
+	if sp, ok := TraceSpan(s); ok {
+		res = l.checkAttributes(res, "span", sp.Attributes())
+		for i := 0; i < sp.Events().Len(); i++ {
+			res = l.checkAttributes(res, "span event", sp.Events().At(i).Attributes())
+		}
+	}
+	if r, ok := LogRecord(s); ok {
+		res = l.checkAttributes(res, "log record", r.Attributes())
+	}
+	if HasMetric(s) {
+		res = l.checkMetric(res, s.Metric)
+		if attrs, ok := DataPointAttributes(s); ok {
+			res = l.checkAttributes(res, "data point", attrs)
+		}
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	return res
+}
+
+func (l *Linter) checkAttributes(res []Violation, target string, attrs pcommon.Map) []Violation {
+	attrs.Range(func(key string, v pcommon.Value) bool {
+		e, ok := l.lookupAttribute(key)
+		if !ok {
+			if ns, _, found := strings.Cut(key, "."); found && l.namespaces[ns] {
+				res = append(res, Violation{Rule: LintUnknown, Key: key,
+					Message: fmt.Sprintf("%s attribute %s is not defined in semconv %s", target, key, l.version)})
+			}
+			return true
+		}
+		if deprecatedIn(e, l.version) {
+			msg := fmt.Sprintf("%s attribute %s is deprecated", target, key)
+			if e.Replacement != "" {
+				msg += ", use " + e.Replacement
+			}
+			res = append(res, Violation{Rule: LintDeprecated, Key: key, Message: msg})
+		}
+		if t := attributeType(v); !compatibleType(e.Type, t) {
+			res = append(res, Violation{Rule: LintType, Key: key,
+				Message: fmt.Sprintf("%s attribute %s is %s, expected %s", target, key, t, e.Type)})
+		}
+		return true
+	})
+	return res
+}
+
+func (l *Linter) lookupAttribute(key string) (semconvEntry, bool) {
+	if e, ok := l.attributes[key]; ok {
+		return e, true
+	}
+	for _, e := range l.templates {
+		if strings.HasPrefix(key, e.Name+".") {
+			return e, true
+		}
+	}
+	return semconvEntry{}, false
+}
+
+func (l *Linter) checkMetric(res []Violation, m pmetric.Metric) []Violation {
+	name, unit := m.Name(), m.Unit()
+	if e, ok := l.metrics[name]; ok {
+		if deprecatedIn(e, l.version) {
+			msg := fmt.Sprintf("metric %s is deprecated", name)
+			if e.Replacement != "" {
+				msg += ", use " + e.Replacement
+			}
+			res = append(res, Violation{Rule: LintDeprecated, Key: name, Message: msg})
+		}
+		if unit != e.Unit {
+			res = append(res, Violation{Rule: LintUnit, Key: name,
+				Message: fmt.Sprintf("unit %q of metric %s, expected %q", unit, name, e.Unit)})
+		}
+		return res
+	}
+	if ns, _, found := strings.Cut(name, "."); found && l.namespaces[ns] && l.metricNamespace(ns) {
+		res = append(res, Violation{Rule: LintUnknown, Key: name,
+			Message: fmt.Sprintf("metric %s is not defined in semconv %s", name, l.version)})
+	}
+	if code, ok := nonUCUMUnits[unit]; ok {
+		res = append(res, Violation{Rule: LintUnit, Key: name,
+			Message: fmt.Sprintf("unit %q of metric %s is not UCUM, use %q", unit, name, code)})
+	} else if isWord(unit) && !ucumUnits[unit] {
+		res = append(res, Violation{Rule: LintUnit, Key: name,
+			Message: fmt.Sprintf("unit %q of metric %s is not UCUM, use an annotation like \"{%s}\"", unit, name, unit)})
+	}
+	return res
+}
+
+// metricNamespace returns true if the registry defines metrics in the namespace.
+func (l *Linter) metricNamespace(ns string) bool {
+	for name := range l.metrics {
+		if strings.HasPrefix(name, ns+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// isWord returns true for units like "requests", longer than UCUM codes of
+// the base units.
+func isWord(unit string) bool {
+	if len(unit) < 3 {
+		return false
+	}
+	for _, r := range unit {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// attributeType returns the type name used by the registry, e.g. string[].
+func attributeType(v pcommon.Value) string {
+	switch v.Type() {
+	case pcommon.ValueTypeStr:
+		return "string"
+	case pcommon.ValueTypeInt:
+		return "int"
+	case pcommon.ValueTypeDouble:
+		return "double"
+	case pcommon.ValueTypeBool:
+		return "boolean"
+	case pcommon.ValueTypeSlice:
+		if v.Slice().Len() == 0 {
+			return "[]"
+		}
+		return attributeType(v.Slice().At(0)) + "[]"
+	case pcommon.ValueTypeMap:
+		return "map"
+	case pcommon.ValueTypeBytes:
+		return "bytes"
+	}
+	return "empty"
+}
+
+// compatibleType accepts integers for doubles and empty arrays of any type.
+func compatibleType(expected string, actual string) bool {
+	switch {
+	case expected == actual:
+		return true
+	case expected == "double" && actual == "int":
+		return true
+	case expected == "double[]" && actual == "int[]":
+		return true
+	case actual == "[]" && strings.HasSuffix(expected, "[]"):
+		return true
+	}
+	return false
+}
+
+func lintProperties(violations []Violation) Properties {
+	props := NewPropsContainer("Lint")
+	for _, v := range violations {
+		props.AddString(v.Rule+" "+v.Key, v.Message)
+	}
+	return props
+}
@@ -0,0 +1,118 @@
+package probe
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func lintMessages(violations []Violation) []string {
+	res := make([]string, 0, len(violations))
+	for _, v := range violations {
+		res = append(res, v.Rule+": "+v.Message)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func TestLintSpan(t *testing.T) {
+
+	l, err := NewLinter("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	span := ptrace.NewSpan()
+	span.Attributes().PutStr("http.method", "GET")
+	span.Attributes().PutStr("http.response.status_code", "200")
+	span.Attributes().PutStr("http.request.foo", "x")
+	span.Attributes().PutEmptySlice("http.request.header.accept").AppendEmpty().SetStr("*/*")
+	span.Attributes().PutStr("app.order_id", "42")
+	span.Attributes().PutInt("server.port", 443)
+	span.Events().AppendEmpty().Attributes().PutStr("exception.escaped", "true")
+
+	s := newSpanSignal(pcommon.NewResource(), pcommon.NewInstrumentationScope(), span)
+	expected := []string{
+		"deprecated: span attribute http.method is deprecated, use http.request.method",
+		"missing: resource attribute service.name is missing",
+		"type: span attribute http.response.status_code is string, expected int",
+		"type: span event attribute exception.escaped is string, expected boolean",
+		"unknown: span attribute http.request.foo is not defined in semconv 1.26.0",
+	}
+	if got := lintMessages(l.Check(s)); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("invalid violations => %v", got)
+	}
+
+	// older versions don't know the new attributes yet
+	old, err := NewLinter("1.20.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := pcommon.NewResource()
+	res.Attributes().PutStr("service.name", "checkout")
+	span = ptrace.NewSpan()
+	span.Attributes().PutStr("http.method", "GET")
+	span.Attributes().PutStr("http.request.method", "GET")
+	got := lintMessages(old.Check(newSpanSignal(res, pcommon.NewInstrumentationScope(), span)))
+	if len(got) != 1 || got[0] != "unknown: span attribute http.request.method is not defined in semconv 1.20.0" {
+		t.Errorf("invalid violations of 1.20.0 => %v", got)
+	}
+
+	if _, err := NewLinter("0.1.0"); err == nil {
+		t.Errorf("unsupported version should fail")
+	}
+
+}
+
+func TestLintMetric(t *testing.T) {
+
+	l, _ := NewLinter("1.26.0")
+	res := pcommon.NewResource()
+	res.Attributes().PutStr("service.name", "checkout")
+	for _, c := range []struct {
+		name     string
+		unit     string
+		expected []string
+	}{
+		{"http.server.request.duration", "s", []string{}},
+		{"http.server.request.duration", "ms", []string{`unit: unit "ms" of metric http.server.request.duration, expected "s"`}},
+		{"http.server.duration", "ms", []string{"deprecated: metric http.server.duration is deprecated, use http.server.request.duration"}},
+		{"http.server.foo", "1", []string{"unknown: metric http.server.foo is not defined in semconv 1.26.0"}},
+		{"app.queue.latency", "seconds", []string{`unit: unit "seconds" of metric app.queue.latency is not UCUM, use "s"`}},
+		{"app.orders", "orders", []string{`unit: unit "orders" of metric app.orders is not UCUM, use an annotation like "{orders}"`}},
+		{"app.orders", "{order}", []string{}},
+		{"app.size", "KiBy", []string{}},
+	} {
+		m := pmetric.NewMetric()
+		m.SetName(c.name)
+		m.SetUnit(c.unit)
+		dp := m.SetEmptyGauge().DataPoints().AppendEmpty()
+		got := lintMessages(l.Check(newMetricSignal(res, pcommon.NewInstrumentationScope(), m, dp)))
+		if strings.Join(got, "\n") != strings.Join(c.expected, "\n") {
+			t.Errorf("invalid violations of %s [%s] => %v", c.name, c.unit, got)
+		}
+	}
+
+}
+
+func TestServerLint(t *testing.T) {
+
+	ch := make(chan *Signal, 10)
+	server := NewServer(0, 0, ch)
+	server.Linter, _ = NewLinter("")
+	if err := server.Process(context.Background(), OTLPData{Kind: METRIC, Metrics: newTestMetrics()}); err != nil {
+		t.Fatal(err)
+	}
+	s := <-ch
+	if len(s.Lint) == 0 || s.Lint[0].Rule != LintMissing {
+		t.Errorf("signal should be linted => %v", s.Lint)
+	}
+	if props := s.Properties(); props[0].Name() != "Lint" || !containsProp(props[0].Get(), "missing service.name", "resource attribute service.name is missing") {
+		t.Errorf("invalid lint section => %v", props[0].Get())
+	}
+
+}
@@ -0,0 +1,19 @@
+package probe
+
+import (
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+func logProperties(r plog.LogRecord) *PropsContainer {
+	props := NewPropsContainer("Record")
+	props.AddMap(r.Attributes(), "Attributes")
+	props.AddTimestamp("Timestamp", r.Timestamp())
+	props.AddTimestamp("ObservedTimestamp", r.ObservedTimestamp())
+	props.AddString("TraceId", r.TraceID().String())
+	props.AddString("SpanId", r.SpanID().String())
+	props.AddBool("Flags.IsSampled", r.Flags().IsSampled())
+	props.AddString("SeverityText", r.SeverityText())
+	props.AddString("SeverityNumber", r.SeverityNumber().String())
+	props.AddValue("Body", r.Body())
+	return props
+}
@@ -0,0 +1,314 @@
+package probe
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// histBucket is a single histogram bucket with its boundaries.
+type histBucket struct {
+	lower float64
+	upper float64
+	count uint64
+}
+
+var summaryQuantiles = []float64{0.5, 0.9, 0.99}
+
+func FormatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// indexedName returns name with zero-padded index, so the entries keep their
+// order when properties are sorted.
+func indexedName(prefix string, i int, n int) string {
+	return fmt.Sprintf("%s[%0*d]", prefix, len(strconv.Itoa(max(n-1, 0))), i)
+}
+
+// quantileName formats quantile as pNN, e.g. 0.5 => p50, 0.999 => p99.9.
+func quantileName(q float64) string {
+	return "p" + strconv.FormatFloat(q*100, 'f', -1, 64)
+}
+
+// estimateQuantile estimates the quantile from the buckets using linear
+// interpolation within the bucket (in the same way as Prometheus does).
+func estimateQuantile(q float64, buckets []histBucket) (float64, bool) {
+	var total uint64
+	for _, b := range buckets {
+		total += b.count
+	}
+	if total == 0 {
+		return 0, false
+	}
+	rank := q * float64(total)
+	var cum float64
+	for _, b := range buckets {
+		if b.count == 0 {
+			continue
+		}
+		if cum+float64(b.count) >= rank {
+			return b.lower + (b.upper-b.lower)*(rank-cum)/float64(b.count), true
+		}
+		cum += float64(b.count)
+	}
+	last := buckets[len(buckets)-1]
+	return last.upper, true
+}
+
+// histogramBuckets converts explicit bounds to buckets, the outer boundaries
+// are taken from min/max if they are available.
+func histogramBuckets(dpp pmetric.HistogramDataPoint) []histBucket {
+	bounds := dpp.ExplicitBounds().AsRaw()
+	counts := dpp.BucketCounts().AsRaw()
+	if len(counts) == 0 {
+		return nil
+	}
+	lower, upper := math.Inf(-1), math.Inf(1)
+	if len(bounds) > 0 {
+		lower, upper = min(bounds[0], 0), bounds[len(bounds)-1]
+	}
+	if dpp.HasMin() {
+		lower = dpp.Min()
+	}
+	if dpp.HasMax() {
+		upper = dpp.Max()
+	}
+	buckets := make([]histBucket, 0, len(counts))
+	for i, c := range counts {
+		b := histBucket{lower: lower, upper: upper, count: c}
+		if i > 0 && i-1 < len(bounds) {
+			b.lower = bounds[i-1]
+		}
+		if i < len(bounds) {
+			b.upper = bounds[i]
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets
+}
+
+// expHistogramBuckets returns buckets in ascending order: negative ones,
+// the zero bucket and positive ones.
+func expHistogramBuckets(dpp pmetric.ExponentialHistogramDataPoint) []histBucket {
+	bound := func(idx int) float64 {
+		return expBucketBound(dpp.Scale(), idx)
+	}
+	buckets := make([]histBucket, 0)
+	neg := dpp.Negative()
+	for i := neg.BucketCounts().Len() - 1; i >= 0; i-- {
+		idx := int(neg.Offset()) + i
+		buckets = append(buckets, histBucket{lower: -bound(idx + 1), upper: -bound(idx), count: neg.BucketCounts().At(i)})
+	}
+	buckets = append(buckets, histBucket{lower: -dpp.ZeroThreshold(), upper: dpp.ZeroThreshold(), count: dpp.ZeroCount()})
+	pos := dpp.Positive()
+	for i := 0; i < pos.BucketCounts().Len(); i++ {
+		idx := int(pos.Offset()) + i
+		buckets = append(buckets, histBucket{lower: bound(idx), upper: bound(idx + 1), count: pos.BucketCounts().At(i)})
+	}
+	return buckets
+}
+
+// expBucketBound returns lower boundary of the positive bucket with given index,
+// i.e. base^idx where base = 2^(2^-scale).
+func expBucketBound(scale int32, idx int) float64 {
+	return math.Exp2(float64(idx) * math.Exp2(-float64(scale)))
+}
+
+func formatBucket(b histBucket) string {
+	return fmt.Sprintf("(%s, %s]", FormatFloat(b.lower), FormatFloat(b.upper))
+}
+
+// distributionSummary builds summary line like: latency count=12 sum=3.4 p50≈0.2 p90≈0.5
+func distributionSummary(name string, count uint64, sum float64, hasSum bool, quantiles []string) string {
+	parts := []string{name, fmt.Sprintf("count=%d", count)}
+	if hasSum {
+		parts = append(parts, "sum="+FormatFloat(sum))
+	}
+	return strings.Join(append(parts, quantiles...), " ")
+}
+
+func estimatedQuantiles(buckets []histBucket) []string {
+	res := make([]string, 0, len(summaryQuantiles))
+	for _, q := range summaryQuantiles {
+		if v, ok := estimateQuantile(q, buckets); ok && !math.IsNaN(v) && !math.IsInf(v, 0) {
+			res = append(res, fmt.Sprintf("%s≈%.4g", quantileName(q), v))
+		}
+	}
+	return res
+}
+
+func histogramSummary(name string, dpp pmetric.HistogramDataPoint) string {
+	return distributionSummary(name, dpp.Count(), dpp.Sum(), dpp.HasSum(), estimatedQuantiles(histogramBuckets(dpp)))
+}
+
+func expHistogramSummary(name string, dpp pmetric.ExponentialHistogramDataPoint) string {
+	return distributionSummary(name, dpp.Count(), dpp.Sum(), dpp.HasSum(), estimatedQuantiles(expHistogramBuckets(dpp)))
+}
+
+func summarySummary(name string, dpp pmetric.SummaryDataPoint) string {
+	qv := dpp.QuantileValues()
+	quantiles := make([]string, 0, qv.Len())
+	for i := 0; i < qv.Len(); i++ {
+		quantiles = append(quantiles, fmt.Sprintf("%s=%.4g", quantileName(qv.At(i).Quantile()), qv.At(i).Value()))
+	}
+	return distributionSummary(name, dpp.Count(), dpp.Sum(), true, quantiles)
+}
+
+func numberValueString(dpp pmetric.NumberDataPoint) string {
+	switch dpp.ValueType() {
+	case pmetric.NumberDataPointValueTypeInt:
+		return fmt.Sprintf("%v", dpp.IntValue())
+	case pmetric.NumberDataPointValueTypeDouble:
+		return fmt.Sprintf("%v", dpp.DoubleValue())
+	}
+	return "N/A"
+}
+
+// AttributesString formats attributes sorted by key, e.g. method=GET,status=200
+func AttributesString(attrs pcommon.Map) string {
+	res := make([]string, 0, attrs.Len())
+	attrs.Range(func(k string, v pcommon.Value) bool {
+		res = append(res, k+"="+v.AsString())
+		return true
+	})
+	sort.Strings(res)
+	return strings.Join(res, ",")
+}
+
+func metricProperties(m pmetric.Metric) *PropsContainer {
+	props := NewPropsContainer("Metric")
+	props.AddString("Type", m.Type().String())
+	props.AddString("Name", m.Name())
+	props.AddString("Unit", m.Unit())
+	props.AddString("Description", m.Description())
+	switch m.Type() {
+	case pmetric.MetricTypeSum:
+		props.AddString("AggregationTemporality", m.Sum().AggregationTemporality().String())
+		props.AddBool("IsMonotonic", m.Sum().IsMonotonic())
+	case pmetric.MetricTypeHistogram:
+		props.AddString("AggregationTemporality", m.Histogram().AggregationTemporality().String())
+	case pmetric.MetricTypeExponentialHistogram:
+		props.AddString("AggregationTemporality", m.ExponentialHistogram().AggregationTemporality().String())
+	}
+	return props
+}
+
+func numberProps(dpp pmetric.NumberDataPoint) *PropsContainer {
+	props := NewPropsContainer("DataPoint")
+	props.AddMap(dpp.Attributes(), "Attributes")
+	props.AddBool("Flags.NoRecordedValue", dpp.Flags().NoRecordedValue())
+	props.AddTimestamp("StartTimestamp", dpp.StartTimestamp())
+	props.AddTimestamp("Timestamp", dpp.Timestamp())
+	props.AddUInt32("Exemplars", uint32(dpp.Exemplars().Len()))
+	props.AddString("Value", numberValueString(dpp))
+	props.AddString("ValueType", dpp.ValueType().String())
+	return props
+}
+
+func histogramProps(dpp pmetric.HistogramDataPoint) *PropsContainer {
+	props := NewPropsContainer("DataPoint")
+	props.AddMap(dpp.Attributes(), "Attributes")
+	props.AddBool("Flags.NoRecordedValue", dpp.Flags().NoRecordedValue())
+	props.AddTimestamp("StartTimestamp", dpp.StartTimestamp())
+	props.AddTimestamp("Timestamp", dpp.Timestamp())
+	props.AddUInt64("Count", dpp.Count())
+	addOptionalFloat64(props, "Sum", dpp.Sum(), dpp.HasSum())
+	addOptionalFloat64(props, "Min", dpp.Min(), dpp.HasMin())
+	addOptionalFloat64(props, "Max", dpp.Max(), dpp.HasMax())
+	props.AddUInt32("Exemplars", uint32(dpp.Exemplars().Len()))
+	bounds := dpp.ExplicitBounds()
+	counts := dpp.BucketCounts()
+	for i := 0; i < counts.Len(); i++ {
+		le := "+Inf"
+		if i < bounds.Len() {
+			le = FormatFloat(bounds.At(i))
+		}
+		props.AddString(indexedName("Bucket", i, counts.Len()), fmt.Sprintf("le %s: %d", le, counts.At(i)))
+	}
+	return props
+}
+
+func expHistogramProps(dpp pmetric.ExponentialHistogramDataPoint) *PropsContainer {
+	props := NewPropsContainer("DataPoint")
+	props.AddMap(dpp.Attributes(), "Attributes")
+	props.AddBool("Flags.NoRecordedValue", dpp.Flags().NoRecordedValue())
+	props.AddTimestamp("StartTimestamp", dpp.StartTimestamp())
+	props.AddTimestamp("Timestamp", dpp.Timestamp())
+	props.AddUInt64("Count", dpp.Count())
+	addOptionalFloat64(props, "Sum", dpp.Sum(), dpp.HasSum())
+	addOptionalFloat64(props, "Min", dpp.Min(), dpp.HasMin())
+	addOptionalFloat64(props, "Max", dpp.Max(), dpp.HasMax())
+	props.AddUInt32("Exemplars", uint32(dpp.Exemplars().Len()))
+	props.AddString("Scale", strconv.Itoa(int(dpp.Scale())))
+	props.AddUInt64("ZeroCount", dpp.ZeroCount())
+	props.AddFloat64("ZeroThreshold", dpp.ZeroThreshold())
+	for _, side := range []struct {
+		name    string
+		buckets pmetric.ExponentialHistogramDataPointBuckets
+		sign    float64
+	}{{"Positive", dpp.Positive(), 1}, {"Negative", dpp.Negative(), -1}} {
+		counts := side.buckets.BucketCounts()
+		props.AddString(side.name+".Offset", strconv.Itoa(int(side.buckets.Offset())))
+		for i := 0; i < counts.Len(); i++ {
+			idx := int(side.buckets.Offset()) + i
+			b := histBucket{lower: expBucketBound(dpp.Scale(), idx), upper: expBucketBound(dpp.Scale(), idx+1)}
+			if side.sign < 0 {
+				b.lower, b.upper = -b.upper, -b.lower
+			}
+			props.AddString(indexedName(side.name+".Bucket", i, counts.Len()), fmt.Sprintf("%s: %d", formatBucket(b), counts.At(i)))
+		}
+	}
+	return props
+}
+
+func summaryProps(dpp pmetric.SummaryDataPoint) *PropsContainer {
+	props := NewPropsContainer("DataPoint")
+	props.AddMap(dpp.Attributes(), "Attributes")
+	props.AddBool("Flags.NoRecordedValue", dpp.Flags().NoRecordedValue())
+	props.AddTimestamp("StartTimestamp", dpp.StartTimestamp())
+	props.AddTimestamp("Timestamp", dpp.Timestamp())
+	props.AddUInt64("Count", dpp.Count())
+	props.AddFloat64("Sum", dpp.Sum())
+	qv := dpp.QuantileValues()
+	for i := 0; i < qv.Len(); i++ {
+		props.AddFloat64(indexedName("Quantile", i, qv.Len())+" "+quantileName(qv.At(i).Quantile()), qv.At(i).Value())
+	}
+	return props
+}
+
+func addOptionalFloat64(props Properties, name string, value float64, ok bool) {
+	if ok {
+		props.AddFloat64(name, value)
+	} else {
+		props.AddString(name, "N/A")
+	}
+}
+
+// dataPointProperties returns sections of the data point followed by its exemplars
+// and the rest of sections (metric, scope, resource).
+func dataPointProperties(dpProps Properties, exemplars pmetric.ExemplarSlice, rest ...Properties) []Properties {
+	props := []Properties{dpProps}
+	for i := 0; i < exemplars.Len(); i++ {
+		ex := exemplars.At(i)
+		exProps := NewPropsContainer(indexedName("Exemplar", i, exemplars.Len()))
+		exProps.AddMap(ex.FilteredAttributes(), "FilteredAttributes")
+		exProps.AddTimestamp("Timestamp", ex.Timestamp())
+		exProps.AddString("TraceId", ex.TraceID().String())
+		exProps.AddString("SpanId", ex.SpanID().String())
+		switch ex.ValueType() {
+		case pmetric.ExemplarValueTypeInt:
+			exProps.AddString("Value", strconv.FormatInt(ex.IntValue(), 10))
+		case pmetric.ExemplarValueTypeDouble:
+			exProps.AddFloat64("Value", ex.DoubleValue())
+		default:
+			exProps.AddString("Value", "N/A")
+		}
+		props = append(props, exProps)
+	}
+	return append(props, rest...)
+}
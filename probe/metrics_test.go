@@ -0,0 +1,132 @@
+package probe
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func newTestMetrics() pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	ms := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+
+	h := ms.AppendEmpty()
+	h.SetName("latency")
+	hdp := h.SetEmptyHistogram().DataPoints().AppendEmpty()
+	hdp.Attributes().PutStr("route", "/orders")
+	hdp.SetCount(10)
+	hdp.SetSum(3.4)
+	hdp.ExplicitBounds().FromRaw([]float64{0.1, 0.5, 1})
+	hdp.BucketCounts().FromRaw([]uint64{2, 6, 2, 0})
+	ex := hdp.Exemplars().AppendEmpty()
+	ex.SetDoubleValue(0.42)
+	ex.SetTraceID([16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+
+	// empty histogram
+	ms.AppendEmpty().SetEmptyHistogram().DataPoints().AppendEmpty()
+
+	e := ms.AppendEmpty()
+	e.SetName("size")
+	edp := e.SetEmptyExponentialHistogram().DataPoints().AppendEmpty()
+	edp.SetScale(0)
+	edp.SetCount(7)
+	edp.SetZeroCount(1)
+	edp.Positive().SetOffset(1)
+	edp.Positive().BucketCounts().FromRaw([]uint64{2, 4})
+
+	s := ms.AppendEmpty()
+	s.SetName("rpc")
+	sdp := s.SetEmptySummary().DataPoints().AppendEmpty()
+	sdp.SetCount(12)
+	sdp.SetSum(3.4)
+	q := sdp.QuantileValues().AppendEmpty()
+	q.SetQuantile(0.5)
+	q.SetValue(0.25)
+
+	return md
+}
+
+func TestProcessMetricsDistributions(t *testing.T) {
+
+	ch := make(chan *Signal, 10)
+	server := NewServer(0, 0, ch)
+	md := newTestMetrics()
+	if err := server.processMetrics(context.Background(), &md); err != nil {
+		t.Fatal(err)
+	}
+	if len(ch) != 4 {
+		t.Fatalf("invalid number of signals => %d", len(ch))
+	}
+
+	hist := <-ch
+	if hist.Summary() != "latency count=10 sum=3.4 p50≈0.3 p90≈0.75 p99≈0.975" {
+		t.Errorf("invalid histogram summary => %v", hist.Summary())
+	}
+	dp := hist.Properties()[0].Get()
+	if !containsProp(dp, "Attributes.route", "/orders") || !containsProp(dp, "Bucket[1]", "le 0.5: 6") ||
+		!containsProp(dp, "Bucket[3]", "le +Inf: 0") || !containsProp(dp, "Min", "N/A") {
+		t.Errorf("invalid histogram properties => %v", dp)
+	}
+	if hist.Properties()[1].Name() != "Exemplar[0]" || !containsProp(hist.Properties()[1].Get(), "Value", "0.42") ||
+		!containsProp(hist.Properties()[1].Get(), "TraceId", "0102030405060708090a0b0c0d0e0f10") {
+		t.Errorf("invalid exemplar => %v %v", hist.Properties()[1].Name(), hist.Properties()[1].Get())
+	}
+	for _, p := range hist.Properties()[2].Get() {
+		if strings.HasPrefix(p[0], "Flags") {
+			t.Errorf("flags should not be added to the metric => %v", p)
+		}
+	}
+
+	empty := <-ch
+	if empty.Summary() != " count=0" {
+		t.Errorf("invalid empty histogram summary => %v", empty.Summary())
+	}
+
+	exp := <-ch
+	if exp.Summary() != "size count=7 p50≈4.5 p90≈7.3 p99≈7.93" {
+		t.Errorf("invalid exponential histogram summary => %v", exp.Summary())
+	}
+	dp = exp.Properties()[0].Get()
+	if !containsProp(dp, "Positive.Bucket[0]", "(2, 4]: 2") || !containsProp(dp, "ZeroCount", "1") {
+		t.Errorf("invalid exponential histogram properties => %v", dp)
+	}
+
+	sum := <-ch
+	if sum.Summary() != "rpc count=12 sum=3.4 p50=0.25" {
+		t.Errorf("invalid summary summary => %v", sum.Summary())
+	}
+	if !containsProp(sum.Properties()[0].Get(), "Quantile[0] p50", "0.25") {
+		t.Errorf("invalid summary properties => %v", sum.Properties()[0].Get())
+	}
+
+}
+
+func TestEstimateQuantile(t *testing.T) {
+
+	buckets := []histBucket{{0, 1, 0}, {1, 2, 4}, {2, 4, 4}}
+	if v, ok := estimateQuantile(0.5, buckets); !ok || v != 2 {
+		t.Errorf("invalid estimateQuantile() => %v, %v", v, ok)
+	}
+	if v, ok := estimateQuantile(0.75, buckets); !ok || v != 3 {
+		t.Errorf("invalid estimateQuantile() => %v, %v", v, ok)
+	}
+	if _, ok := estimateQuantile(0.5, []histBucket{{0, 1, 0}}); ok {
+		t.Errorf("empty buckets should not have quantile")
+	}
+	if v := expBucketBound(1, 3); math.Abs(v-math.Pow(math.Sqrt2, 3)) > 1e-9 {
+		t.Errorf("invalid expBucketBound() => %v", v)
+	}
+
+}
+
+func containsProp(props [][]string, name string, value string) bool {
+	for _, p := range props {
+		if p[0] == name && p[1] == value {
+			return true
+		}
+	}
+	return false
+}
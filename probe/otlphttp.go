@@ -0,0 +1,178 @@
+package probe
+
+import (
+	"errors"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+type payloadEncoding int
+
+const (
+	encodingProto payloadEncoding = iota
+	encodingJSON  payloadEncoding = iota
+)
+
+const (
+	ContentTypeProto = "application/x-protobuf"
+	ContentTypeJSON  = "application/json"
+)
+
+// the same default as the collector's confighttp max_request_body_size
+const DefaultMaxRequestSize = 20 << 20
+
+// otlpRequest is implemented by plogotlp, pmetricotlp and ptraceotlp export requests.
+type otlpRequest interface {
+	UnmarshalProto(data []byte) error
+	UnmarshalJSON(data []byte) error
+}
+
+// otlpResponse is implemented by plogotlp, pmetricotlp and ptraceotlp export responses.
+type otlpResponse interface {
+	MarshalProto() ([]byte, error)
+	MarshalJSON() ([]byte, error)
+}
+
+func (e payloadEncoding) contentType() string {
+	if e == encodingJSON {
+		return ContentTypeJSON
+	}
+	return ContentTypeProto
+}
+
+// requestEncoding maps Content-Type of the request to payload encoding.
+// Missing Content-Type is treated as protobuf, unknown one is an error.
+func requestEncoding(req *http.Request) (payloadEncoding, bool) {
+	ct := req.Header.Get("Content-Type")
+	if ct == "" {
+		return encodingProto, true
+	}
+	mediaType, _, _ := mime.ParseMediaType(ct)
+	switch mediaType {
+	case ContentTypeProto:
+		return encodingProto, true
+	case ContentTypeJSON:
+		return encodingJSON, true
+	}
+	return encodingProto, false
+}
+
+// readRequest decodes body of OTLP/HTTP request into preq. It writes an error
+// response and returns false if the request can't be processed.
+func (server *Server) readRequest(resp http.ResponseWriter, req *http.Request, preq otlpRequest) (payloadEncoding, bool) {
+	enc, supported := requestEncoding(req)
+	if req.Method != http.MethodPost {
+		resp.Header().Set("Allow", http.MethodPost)
+		writeError(resp, enc, http.StatusMethodNotAllowed, status.New(codes.Unimplemented, "method not allowed"))
+		return enc, false
+	}
+	if !supported {
+		writeError(resp, enc, http.StatusUnsupportedMediaType,
+			status.Newf(codes.InvalidArgument, "unsupported Content-Type: %s", req.Header.Get("Content-Type")))
+		return enc, false
+	}
+	body := req.Body
+	if server.MaxRequestSize > 0 {
+		body = http.MaxBytesReader(resp, body, server.MaxRequestSize)
+	}
+	data, err := decompressBody(req.Header.Get("Content-Encoding"), body, server.MaxRequestSize)
+	var maxBytesErr *http.MaxBytesError
+	if errors.Is(err, errPayloadTooLarge) || errors.As(err, &maxBytesErr) {
+		writeError(resp, enc, http.StatusRequestEntityTooLarge,
+			status.Newf(codes.ResourceExhausted, "payload exceeds %d bytes", server.MaxRequestSize))
+		return enc, false
+	} else if err != nil {
+		writeError(resp, enc, http.StatusBadRequest, status.Newf(codes.InvalidArgument, "could not read body: %s", err))
+		return enc, false
+	}
+	if enc == encodingJSON {
+		err = preq.UnmarshalJSON(data)
+	} else {
+		err = preq.UnmarshalProto(data)
+	}
+	if err != nil {
+		writeError(resp, enc, http.StatusBadRequest, status.Newf(codes.InvalidArgument, "could not decode body: %s", err))
+		return enc, false
+	}
+	return enc, true
+}
+
+// writeResponse sends presp using the same encoding as the client used.
+// If err is not nil an error response is sent instead.
+func writeResponse(resp http.ResponseWriter, enc payloadEncoding, presp otlpResponse, err error) {
+	if err != nil {
+		st := status.Convert(err)
+		code := httpStatusFromCode(st.Code())
+		if code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable {
+			for _, d := range st.Details() {
+				if ri, ok := d.(*errdetails.RetryInfo); ok {
+					secs := int(math.Ceil(ri.GetRetryDelay().AsDuration().Seconds()))
+					resp.Header().Set("Retry-After", strconv.Itoa(secs))
+				}
+			}
+		}
+		writeError(resp, enc, code, st)
+		return
+	}
+	var body []byte
+	if enc == encodingJSON {
+		body, err = presp.MarshalJSON()
+	} else {
+		body, err = presp.MarshalProto()
+	}
+	if err != nil {
+		writeError(resp, enc, http.StatusInternalServerError, status.Newf(codes.Internal, "could not encode response: %s", err))
+		return
+	}
+	resp.Header().Set("Content-Type", enc.contentType())
+	resp.WriteHeader(http.StatusOK)
+	resp.Write(body)
+}
+
+// writeError sends google.rpc.Status message as the spec requires.
+func writeError(resp http.ResponseWriter, enc payloadEncoding, httpStatus int, st *status.Status) {
+	var body []byte
+	var err error
+	if enc == encodingJSON {
+		body, err = protojson.Marshal(st.Proto())
+	} else {
+		body, err = proto.Marshal(st.Proto())
+	}
+	if err != nil {
+		http.Error(resp, st.Message(), httpStatus)
+		return
+	}
+	resp.Header().Set("Content-Type", enc.contentType())
+	resp.WriteHeader(httpStatus)
+	resp.Write(body)
+}
+
+// httpStatusFromCode maps gRPC status codes to HTTP ones the same way
+// the collector's OTLP receiver does.
+func httpStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound, codes.Unimplemented:
+		return http.StatusNotFound
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Canceled, codes.DeadlineExceeded, codes.Aborted, codes.OutOfRange, codes.Unavailable, codes.DataLoss:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
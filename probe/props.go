@@ -0,0 +1,152 @@
+package probe
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+type Properties interface {
+	AddMap(attr pcommon.Map, prefix string)
+	AddValue(name string, value pcommon.Value)
+	AddString(name string, value string)
+	AddBool(name string, value bool)
+	AddUInt32(name string, value uint32)
+	AddUInt64(name string, value uint64)
+	AddFloat64(name string, value float64)
+	AddTimestamp(name string, value pcommon.Timestamp)
+	Name() string
+	Get() [][]string
+	Entries() []Property
+}
+
+// Property is a single named value. Values which come from OTLP attributes
+// (or log body) keep their typed value, so nested maps and slices can be
+// presented as a tree.
+type Property struct {
+	Name  string
+	Text  string
+	Value pcommon.Value
+	Typed bool
+}
+
+type PropsContainer struct {
+	name  string
+	props []Property
+}
+
+func (a PropsContainer) Name() string {
+	return a.name
+}
+
+func NewPropsContainer(name string) *PropsContainer {
+	a := PropsContainer{name: name}
+	a.props = make([]Property, 0)
+	return &a
+}
+
+func (a *PropsContainer) AddMap(attr pcommon.Map, prefix string) {
+	if len(prefix) > 0 {
+		prefix = prefix + "."
+	}
+	attr.Range(func(k string, v pcommon.Value) bool {
+		a.AddValue(fmt.Sprintf("%s%s", prefix, k), v)
+		return true
+	})
+}
+
+func (a *PropsContainer) AddValue(name string, value pcommon.Value) {
+	a.add(Property{Name: name, Text: value.AsString(), Value: value, Typed: true})
+}
+
+func (a *PropsContainer) AddString(name string, value string) {
+	a.add(Property{Name: name, Text: value})
+}
+
+func (a *PropsContainer) AddBool(name string, value bool) {
+	vtxt := "False"
+	if value {
+		vtxt = "True"
+	}
+	a.add(Property{Name: name, Text: vtxt})
+}
+
+func (a *PropsContainer) AddUInt32(name string, value uint32) {
+	a.add(Property{Name: name, Text: fmt.Sprintf("%d", value)})
+}
+
+func (a *PropsContainer) AddUInt64(name string, value uint64) {
+	a.add(Property{Name: name, Text: fmt.Sprintf("%d", value)})
+}
+
+func (a *PropsContainer) AddFloat64(name string, value float64) {
+	a.add(Property{Name: name, Text: FormatFloat(value)})
+}
+
+func (a *PropsContainer) AddTimestamp(name string, value pcommon.Timestamp) {
+	vtxt := "N/A"
+	if value > 0 {
+		vtxt = value.AsTime().String()
+	}
+	a.add(Property{Name: name, Text: vtxt})
+}
+
+// Entries returns sorted properties, they are shared by readers of the signal
+// and must not be changed.
+func (a PropsContainer) Entries() []Property {
+	return a.props
+}
+
+// add inserts the property keeping them sorted by name, so containers are
+// sorted once when they are built.
+func (a *PropsContainer) add(p Property) {
+	if n := len(a.props); n == 0 || !lessPropertyName(p.Name, a.props[n-1].Name) {
+		a.props = append(a.props, p)
+		return
+	}
+	i := sort.Search(len(a.props), func(i int) bool {
+		return lessPropertyName(p.Name, a.props[i].Name)
+	})
+	a.props = slices.Insert(a.props, i, p)
+}
+
+// lessPropertyName compares names part by part, case-insensitive.
+func lessPropertyName(a string, b string) bool {
+	ri := strings.Split(strings.ToLower(a), ".")
+	rj := strings.Split(strings.ToLower(b), ".")
+	for k := 0; k < max(len(ri), len(rj)); k++ {
+		if len(ri) == k || len(rj) == k {
+			break
+		}
+		if ri[k] != rj[k] {
+			return ri[k] < rj[k]
+		}
+	}
+	return false
+}
+
+// Get returns sorted properties as name/value pairs, nested values are flattened.
+func (a PropsContainer) Get() [][]string {
+	entries := a.Entries()
+	res := make([][]string, len(entries))
+	for i, p := range entries {
+		res[i] = []string{p.Name, p.Text}
+	}
+	return res
+}
+
+// TypeName returns short name of the value type used as annotation, e.g. int, map[2].
+func TypeName(v pcommon.Value) string {
+	switch v.Type() {
+	case pcommon.ValueTypeMap:
+		return fmt.Sprintf("map[%d]", v.Map().Len())
+	case pcommon.ValueTypeSlice:
+		return fmt.Sprintf("slice[%d]", v.Slice().Len())
+	case pcommon.ValueTypeBytes:
+		return fmt.Sprintf("bytes[%d]", v.Bytes().Len())
+	}
+	return strings.ToLower(v.Type().String())
+}
@@ -1,4 +1,4 @@
-package main
+package probe
 
 import (
 	"reflect"
@@ -18,24 +18,24 @@ func TestAttrMergerAddLocalAttrs(t *testing.T) {
 	la1.PutStr("lattr1", "1")
 	la1.PutInt("lattr2", 2)
 
-	res := newPropsContainer("resource")
-	res.addMap(ra1, "")
-	res.addString("props1", "1")
-	res.addBool("props4", true)
-	res.addUInt32("props3", 200)
-	res.addTimestamp("props2", pcommon.Timestamp(uint64(0)))
+	res := NewPropsContainer("resource")
+	res.AddMap(ra1, "")
+	res.AddString("props1", "1")
+	res.AddBool("props4", true)
+	res.AddUInt32("props3", 200)
+	res.AddTimestamp("props2", pcommon.Timestamp(uint64(0)))
 
-	loc := newPropsContainer("signal")
-	loc.addMap(la1, "Attributes")
-	loc.addString("props1", "1")
-	loc.addString("sort.test.a.c.a", "0")
-	loc.addString("sort.test.a.a", "3")
-	loc.addString("sort.test.b.a", "1")
-	loc.addBool("sort.test.a.c", false)
-	loc.addUInt32("sort.test.a.d", 100)
-	loc.addTimestamp("sort.test.a.e", pcommon.NewTimestampFromTime(time.Date(2000, 1, 2, 3, 4, 5, 6, time.UTC)))
+	loc := NewPropsContainer("signal")
+	loc.AddMap(la1, "Attributes")
+	loc.AddString("props1", "1")
+	loc.AddString("sort.test.a.c.a", "0")
+	loc.AddString("sort.test.a.a", "3")
+	loc.AddString("sort.test.b.a", "1")
+	loc.AddBool("sort.test.a.c", false)
+	loc.AddUInt32("sort.test.a.d", 100)
+	loc.AddTimestamp("sort.test.a.e", pcommon.NewTimestampFromTime(time.Date(2000, 1, 2, 3, 4, 5, 6, time.UTC)))
 
-	attr := res.get()
+	attr := res.Get()
 	if !reflect.DeepEqual(attr, [][]string{
 		{"props1", "1"},
 		{"props2", "N/A"},
@@ -47,7 +47,7 @@ func TestAttrMergerAddLocalAttrs(t *testing.T) {
 		t.Errorf("invalid attributes: %v", attr)
 	}
 
-	attr = loc.get()
+	attr = loc.Get()
 	if !reflect.DeepEqual(attr, [][]string{
 		{"Attributes.lattr1", "1"},
 		{"Attributes.lattr2", "2"},
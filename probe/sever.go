@@ -0,0 +1,478 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// RequestHandler gets every received request before faults are injected and
+// its signals are passed to the consumer, e.g. to forward or capture it.
+type RequestHandler interface {
+	HandleMetrics(request pmetricotlp.ExportRequest)
+	HandleLogs(request plogotlp.ExportRequest)
+	HandleTraces(request ptraceotlp.ExportRequest)
+}
+
+// Server receives OTLP requests via gRPC and HTTP and passes every signal
+// to the channel, a request waits until its signals are consumed.
+type Server struct {
+	grpcPort       int
+	httpPort       int
+	TLSConfig      *tls.Config
+	MaxRequestSize int64
+	Faults         *FaultInjector
+	Handlers       []RequestHandler
+	Linter         *Linter // nil disables linting
+	ch             chan *Signal
+
+	mu         sync.Mutex
+	stopped    bool
+	serverGRPC *grpc.Server
+	serverHTTP *http.Server
+}
+
+// NewServer creates the server for Start, zero port disables the receiver.
+// ServeGRPC and ServeOTLPHTTP accept requests on any listener instead.
+func NewServer(grpcPort int, httpPort int, ch chan *Signal) *Server {
+	s := Server{
+		grpcPort:       grpcPort,
+		httpPort:       httpPort,
+		MaxRequestSize: DefaultMaxRequestSize,
+		Faults:         NewFaultInjector(),
+		ch:             ch,
+	}
+	return &s
+}
+
+// lint sets violations of the signal if the linter is enabled.
+func (server *Server) lint(s *Signal) {
+	if server.Linter != nil {
+		s.Lint = server.Linter.Check(s)
+	}
+}
+
+// emit passes the signal to the consumer, it gives up if the request
+// is cancelled in the meantime (e.g. the UI doesn't keep up).
+func (server *Server) emit(ctx context.Context, s *Signal) error {
+	select {
+	case server.ch <- s:
+		return nil
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+// OTLPData is a single request, e.g. read from a file, one of the fields is
+// set depending on the kind. The receive time is known only for captures.
+type OTLPData struct {
+	// ID identifies the request the signals were taken from
+	ID       uint64
+	Kind     KindSignal
+	Received time.Time
+	Metrics  pmetric.Metrics
+	Logs     plog.Logs
+	Traces   ptrace.Traces
+}
+
+var requestID atomic.Uint64
+
+func nextRequestID() uint64 {
+	return requestID.Add(1)
+}
+
+// Signals calls fn for every signal of the data.
+func (d OTLPData) Signals(fn func(s *Signal) error) error {
+	d.ID = nextRequestID()
+	switch d.Kind {
+	case METRIC:
+		return metricSignals(&d, fn)
+	case LOG:
+		return logSignals(&d, fn)
+	case TRACE:
+		return traceSignals(&d, fn)
+	}
+	return nil
+}
+
+// Size returns the size of the data encoded as protobuf.
+func (d OTLPData) Size() int {
+	switch d.Kind {
+	case METRIC:
+		return (&pmetric.ProtoMarshaler{}).MetricsSize(d.Metrics)
+	case LOG:
+		return (&plog.ProtoMarshaler{}).LogsSize(d.Logs)
+	case TRACE:
+		return (&ptrace.ProtoMarshaler{}).TracesSize(d.Traces)
+	}
+	return 0
+}
+
+// Process passes signals of the data read from a file to the consumer.
+func (server *Server) Process(ctx context.Context, d OTLPData) error {
+	switch d.Kind {
+	case METRIC:
+		return server.processMetrics(ctx, &d.Metrics)
+	case LOG:
+		return server.processLogs(ctx, &d.Logs)
+	case TRACE:
+		return server.processTraces(ctx, &d.Traces)
+	}
+	return nil
+}
+
+type metricsServer struct {
+	pmetricotlp.UnimplementedGRPCServer
+	server *Server
+}
+
+type logServer struct {
+	plogotlp.UnimplementedGRPCServer
+	server *Server
+}
+
+type traceServer struct {
+	ptraceotlp.UnimplementedGRPCServer
+	server *Server
+}
+
+// Export methods are used by both gRPC and HTTP receivers.
+
+func (ms metricsServer) Export(ctx context.Context, request pmetricotlp.ExportRequest) (pmetricotlp.ExportResponse, error) {
+	resp := pmetricotlp.NewExportResponse()
+	for _, h := range ms.server.Handlers {
+		h.HandleMetrics(request)
+	}
+	fault := ms.server.Faults.Get(METRIC)
+	if err := fault.inject(ctx); err != nil {
+		return resp, err
+	}
+	m := request.Metrics()
+	if err := ms.server.processMetrics(ctx, &m); err != nil {
+		return resp, err
+	}
+	if n := fault.rejected(m.DataPointCount()); n > 0 {
+		resp.PartialSuccess().SetRejectedDataPoints(n)
+		resp.PartialSuccess().SetErrorMessage(rejectedMessage)
+	}
+	return resp, nil
+}
+
+func (ls logServer) Export(ctx context.Context, request plogotlp.ExportRequest) (plogotlp.ExportResponse, error) {
+	resp := plogotlp.NewExportResponse()
+	for _, h := range ls.server.Handlers {
+		h.HandleLogs(request)
+	}
+	fault := ls.server.Faults.Get(LOG)
+	if err := fault.inject(ctx); err != nil {
+		return resp, err
+	}
+	l := request.Logs()
+	if err := ls.server.processLogs(ctx, &l); err != nil {
+		return resp, err
+	}
+	if n := fault.rejected(l.LogRecordCount()); n > 0 {
+		resp.PartialSuccess().SetRejectedLogRecords(n)
+		resp.PartialSuccess().SetErrorMessage(rejectedMessage)
+	}
+	return resp, nil
+}
+
+func (ls traceServer) Export(ctx context.Context, request ptraceotlp.ExportRequest) (ptraceotlp.ExportResponse, error) {
+	resp := ptraceotlp.NewExportResponse()
+	for _, h := range ls.server.Handlers {
+		h.HandleTraces(request)
+	}
+	fault := ls.server.Faults.Get(TRACE)
+	if err := fault.inject(ctx); err != nil {
+		return resp, err
+	}
+	l := request.Traces()
+	if err := ls.server.processTraces(ctx, &l); err != nil {
+		return resp, err
+	}
+	if n := fault.rejected(l.SpanCount()); n > 0 {
+		resp.PartialSuccess().SetRejectedSpans(n)
+		resp.PartialSuccess().SetErrorMessage(rejectedMessage)
+	}
+	return resp, nil
+}
+
+// Start listens on the configured ports and serves requests, it blocks
+// while the HTTP receiver is running.
+func (server *Server) Start() {
+	if server.grpcPort > 0 {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", server.grpcPort))
+		if err != nil {
+			log.Fatalf("failed to listen: %v", err)
+		}
+		go func() {
+			server.ServeGRPC(lis)
+		}()
+	}
+	if server.httpPort > 0 {
+		server.serverHTTP = &http.Server{Addr: fmt.Sprintf(":%v", server.httpPort), Handler: server.Handler(), TLSConfig: server.TLSConfig}
+		var err error
+		if server.TLSConfig != nil {
+			err = server.serverHTTP.ListenAndServeTLS("", "")
+		} else {
+			err = server.serverHTTP.ListenAndServe()
+		}
+		if errors.Is(err, http.ErrServerClosed) {
+			panic("http server closed\n")
+		} else if err != nil {
+			panic(fmt.Sprintf("error listening http: %s\n", err))
+		}
+	}
+}
+
+// ServeGRPC accepts gRPC requests on the listener until Stop is called.
+func (server *Server) ServeGRPC(lis net.Listener) error {
+	var opts []grpc.ServerOption
+	if server.MaxRequestSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(int(server.MaxRequestSize)))
+	}
+	if server.TLSConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(server.TLSConfig)))
+	}
+	gs := grpc.NewServer(opts...)
+	pmetricotlp.RegisterGRPCServer(gs, &metricsServer{server: server})
+	plogotlp.RegisterGRPCServer(gs, &logServer{server: server})
+	ptraceotlp.RegisterGRPCServer(gs, &traceServer{server: server})
+	server.mu.Lock()
+	if server.stopped {
+		server.mu.Unlock()
+		return lis.Close()
+	}
+	server.serverGRPC = gs
+	server.mu.Unlock()
+	return gs.Serve(lis)
+}
+
+// ServeOTLPHTTP accepts OTLP/HTTP requests on the listener until Stop is called.
+func (server *Server) ServeOTLPHTTP(lis net.Listener) error {
+	hs := &http.Server{Handler: server.Handler(), TLSConfig: server.TLSConfig}
+	server.mu.Lock()
+	if server.stopped {
+		server.mu.Unlock()
+		return lis.Close()
+	}
+	server.serverHTTP = hs
+	server.mu.Unlock()
+	var err error
+	if server.TLSConfig != nil {
+		err = hs.ServeTLS(lis, "", "")
+	} else {
+		err = hs.Serve(lis)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Handler returns the OTLP/HTTP handler of /v1/metrics, /v1/logs and /v1/traces.
+func (server *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/metrics", server.httpMetricHandler)
+	mux.HandleFunc("/v1/logs", server.httpLogHandler)
+	mux.HandleFunc("/v1/traces", server.httpTraceHandler)
+	return mux
+}
+
+// Stop closes listeners of ServeGRPC and ServeOTLPHTTP, requests in progress
+// are cancelled. Listeners passed later are closed immediately.
+func (server *Server) Stop() {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.stopped = true
+	if server.serverGRPC != nil {
+		server.serverGRPC.Stop()
+	}
+	if server.serverHTTP != nil {
+		server.serverHTTP.Close()
+	}
+}
+
+func (server *Server) httpMetricHandler(resp http.ResponseWriter, req *http.Request) {
+	preq := pmetricotlp.NewExportRequest()
+	enc, ok := server.readRequest(resp, req, preq)
+	if !ok {
+		return
+	}
+	presp, err := metricsServer{server: server}.Export(req.Context(), preq)
+	writeResponse(resp, enc, presp, err)
+}
+
+func (server *Server) processMetrics(ctx context.Context, ms *pmetric.Metrics) error {
+	return MetricSignals(*ms, func(s *Signal) error {
+		server.lint(s)
+		return server.emit(ctx, s)
+	})
+}
+
+// MetricSignals calls fn for every data point of the metrics, metrics
+// without data points (e.g. of the empty type) are skipped.
+func MetricSignals(ms pmetric.Metrics, fn func(s *Signal) error) error {
+	return metricSignals(&OTLPData{ID: nextRequestID(), Kind: METRIC, Metrics: ms}, fn)
+}
+
+func metricSignals(d *OTLPData, fn func(s *Signal) error) error {
+	rms := d.Metrics.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+		sms := rm.ScopeMetrics()
+		for j := 0; j < sms.Len(); j++ {
+			sm := sms.At(j)
+			ms := sm.Metrics()
+			for k := 0; k < ms.Len(); k++ {
+				m := ms.At(k)
+				emit := func(dataPoint any) error {
+					s := newMetricSignal(rm.Resource(), sm.Scope(), m, dataPoint)
+					s.Request = d
+					return fn(s)
+				}
+				switch m.Type() {
+				case pmetric.MetricTypeGauge:
+					dp := m.Gauge().DataPoints()
+					for l := 0; l < dp.Len(); l++ {
+						if err := emit(dp.At(l)); err != nil {
+							return err
+						}
+					}
+				case pmetric.MetricTypeSum:
+					dp := m.Sum().DataPoints()
+					for l := 0; l < dp.Len(); l++ {
+						if err := emit(dp.At(l)); err != nil {
+							return err
+						}
+					}
+				case pmetric.MetricTypeHistogram:
+					dp := m.Histogram().DataPoints()
+					for l := 0; l < dp.Len(); l++ {
+						if err := emit(dp.At(l)); err != nil {
+							return err
+						}
+					}
+				case pmetric.MetricTypeExponentialHistogram:
+					dp := m.ExponentialHistogram().DataPoints()
+					for l := 0; l < dp.Len(); l++ {
+						if err := emit(dp.At(l)); err != nil {
+							return err
+						}
+					}
+				case pmetric.MetricTypeSummary: // Summary (Legacy)
+					dp := m.Summary().DataPoints()
+					for l := 0; l < dp.Len(); l++ {
+						if err := emit(dp.At(l)); err != nil {
+							return err
+						}
+					}
+				}
+
+			}
+		}
+	}
+	return nil
+}
+
+func (server *Server) httpLogHandler(resp http.ResponseWriter, req *http.Request) {
+	preq := plogotlp.NewExportRequest()
+	enc, ok := server.readRequest(resp, req, preq)
+	if !ok {
+		return
+	}
+	presp, err := logServer{server: server}.Export(req.Context(), preq)
+	writeResponse(resp, enc, presp, err)
+}
+
+func (server *Server) processLogs(ctx context.Context, ms *plog.Logs) error {
+	return LogSignals(*ms, func(s *Signal) error {
+		server.lint(s)
+		return server.emit(ctx, s)
+	})
+}
+
+// LogSignals calls fn for every log record of the logs.
+func LogSignals(ms plog.Logs, fn func(s *Signal) error) error {
+	return logSignals(&OTLPData{ID: nextRequestID(), Kind: LOG, Logs: ms}, fn)
+}
+
+func logSignals(d *OTLPData, fn func(s *Signal) error) error {
+	rls := d.Logs.ResourceLogs()
+	for i := 0; i < rls.Len(); i++ {
+		rl := rls.At(i)
+		sls := rl.ScopeLogs()
+		for j := 0; j < sls.Len(); j++ {
+			sl := sls.At(j)
+			rs := sl.LogRecords()
+			for k := 0; k < rs.Len(); k++ {
+				s := newLogSignal(rl.Resource(), sl.Scope(), rs.At(k))
+				s.Request = d
+				if err := fn(s); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (server *Server) httpTraceHandler(resp http.ResponseWriter, req *http.Request) {
+	preq := ptraceotlp.NewExportRequest()
+	enc, ok := server.readRequest(resp, req, preq)
+	if !ok {
+		return
+	}
+	presp, err := traceServer{server: server}.Export(req.Context(), preq)
+	writeResponse(resp, enc, presp, err)
+}
+
+func (server *Server) processTraces(ctx context.Context, ts *ptrace.Traces) error {
+	return TraceSignals(*ts, func(s *Signal) error {
+		server.lint(s)
+		return server.emit(ctx, s)
+	})
+}
+
+// TraceSignals calls fn for every span of the traces.
+func TraceSignals(ts ptrace.Traces, fn func(s *Signal) error) error {
+	return traceSignals(&OTLPData{ID: nextRequestID(), Kind: TRACE, Traces: ts}, fn)
+}
+
+func traceSignals(d *OTLPData, fn func(s *Signal) error) error {
+	rss := d.Traces.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		rs := rss.At(i)
+		sss := rs.ScopeSpans()
+		for j := 0; j < sss.Len(); j++ {
+			ss := sss.At(j)
+			spans := ss.Spans()
+			for k := 0; k < spans.Len(); k++ {
+				s := newSpanSignal(rs.Resource(), ss.Scope(), spans.At(k))
+				s.Request = d
+				if err := fn(s); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func newTestTraces() ptraceotlp.ExportRequest {
+	td := ptrace.NewTraces()
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("service.name", "checkout")
+	sp := rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	sp.SetName("POST /orders")
+	sp.SetTraceID([16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+	sp.SetSpanID([8]byte{1, 2, 3, 4, 5, 6, 7, 8})
+	return ptraceotlp.NewExportRequestFromTraces(td)
+}
+
+func TestHttpTraceHandlerEncodings(t *testing.T) {
+
+	preq := newTestTraces()
+	pb, _ := preq.MarshalProto()
+	js, _ := preq.MarshalJSON()
+
+	tests := []struct {
+		contentType string
+		body        []byte
+	}{
+		{ContentTypeProto, pb},
+		{ContentTypeJSON, js},
+		{"application/json; charset=utf-8", js},
+	}
+	for _, tc := range tests {
+		ch := make(chan *Signal, 10)
+		server := NewServer(0, 0, ch)
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/traces", bytes.NewReader(tc.body))
+		req.Header.Set("Content-Type", tc.contentType)
+		rec := httptest.NewRecorder()
+		server.httpTraceHandler(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("invalid status for %v => %d %s", tc.contentType, rec.Code, rec.Body.String())
+		}
+		enc, _ := requestEncoding(req)
+		if rec.Header().Get("Content-Type") != enc.contentType() {
+			t.Errorf("invalid response Content-Type for %v => %v", tc.contentType, rec.Header().Get("Content-Type"))
+		}
+		presp := ptraceotlp.NewExportResponse()
+		var err error
+		if enc == encodingJSON {
+			err = presp.UnmarshalJSON(rec.Body.Bytes())
+		} else {
+			err = presp.UnmarshalProto(rec.Body.Bytes())
+		}
+		if err != nil {
+			t.Errorf("invalid response body for %v => %v", tc.contentType, err)
+		}
+		if len(ch) != 1 {
+			t.Errorf("invalid number of signals for %v => %d", tc.contentType, len(ch))
+		}
+	}
+
+}
+
+func TestHttpTraceHandlerErrors(t *testing.T) {
+
+	preq := newTestTraces()
+	pb, _ := preq.MarshalProto()
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        []byte
+		ctx         context.Context
+		code        int
+	}{
+		{"method", http.MethodGet, ContentTypeProto, nil, context.Background(), http.StatusMethodNotAllowed},
+		{"media type", http.MethodPost, "text/plain", pb, context.Background(), http.StatusUnsupportedMediaType},
+		{"malformed proto", http.MethodPost, ContentTypeProto, []byte("garbage"), context.Background(), http.StatusBadRequest},
+		{"malformed json", http.MethodPost, ContentTypeJSON, []byte("{"), context.Background(), http.StatusBadRequest},
+		{"too large", http.MethodPost, ContentTypeProto, bytes.Repeat([]byte{0}, 2048), context.Background(), http.StatusRequestEntityTooLarge},
+		{"cancelled", http.MethodPost, ContentTypeProto, pb, cancelled, http.StatusServiceUnavailable},
+	}
+	for _, tc := range tests {
+		// unbuffered channel without a reader, emit() waits for the context
+		server := NewServer(0, 0, make(chan *Signal))
+		server.MaxRequestSize = 1024
+
+		req := httptest.NewRequest(tc.method, "/v1/traces", bytes.NewReader(tc.body)).WithContext(tc.ctx)
+		req.Header.Set("Content-Type", tc.contentType)
+		rec := httptest.NewRecorder()
+		server.httpTraceHandler(rec, req)
+
+		if rec.Code != tc.code {
+			t.Errorf("invalid status for %v => %d", tc.name, rec.Code)
+		}
+		st := &spb.Status{}
+		var err error
+		if rec.Header().Get("Content-Type") == ContentTypeJSON {
+			err = protojson.Unmarshal(rec.Body.Bytes(), st)
+		} else {
+			err = proto.Unmarshal(rec.Body.Bytes(), st)
+		}
+		if err != nil || st.Message == "" {
+			t.Errorf("invalid status body for %v => %v, %v", tc.name, st, err)
+		}
+	}
+
+}
+
+func TestGrpcExportCancelled(t *testing.T) {
+
+	server := NewServer(0, 0, make(chan *Signal))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := traceServer{server: server}.Export(ctx, newTestTraces())
+	if status.Code(err) != codes.Canceled {
+		t.Errorf("invalid Export() => %v", err)
+	}
+
+}
+
+// requestCounter is a RequestHandler counting requests.
+type requestCounter struct {
+	metrics, logs, traces int
+}
+
+func (c *requestCounter) HandleMetrics(request pmetricotlp.ExportRequest) { c.metrics++ }
+func (c *requestCounter) HandleLogs(request plogotlp.ExportRequest)       { c.logs++ }
+func (c *requestCounter) HandleTraces(request ptraceotlp.ExportRequest)   { c.traces++ }
+
+func TestHandlersGetRejectedRequests(t *testing.T) {
+
+	server := NewServer(0, 0, make(chan *Signal))
+	counter := &requestCounter{}
+	server.Handlers = append(server.Handlers, counter)
+
+	// requests are handled even if they fail with an injected fault
+	server.Faults.Set(TRACE, FaultConfig{ErrorRate: 1, ErrorCode: codes.Unavailable})
+	if _, err := (traceServer{server: server}).Export(context.Background(), newTestTraces()); err == nil {
+		t.Errorf("request should fail")
+	}
+	// or when they are cancelled before the consumer takes signals
+	ld := plog.NewLogs()
+	ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr("payment delayed")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := logServer{server: server}.Export(ctx, plogotlp.NewExportRequestFromLogs(ld))
+	if status.Code(err) != codes.Canceled {
+		t.Errorf("invalid Export() => %v", err)
+	}
+	if counter.traces != 1 || counter.logs != 1 {
+		t.Errorf("invalid handled requests => %+v", *counter)
+	}
+
+}
+
+func TestZstdBombRejected(t *testing.T) {
+
+	// a few KiB of zstd expanding to 4 MiB
+	td := ptrace.NewTraces()
+	sp := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	sp.Attributes().PutStr("padding", strings.Repeat("a", 4<<20))
+	pb, _ := ptraceotlp.NewExportRequestFromTraces(td).MarshalProto()
+	var zs bytes.Buffer
+	w, _ := zstd.NewWriter(&zs)
+	w.Write(pb)
+	w.Close()
+
+	server := NewServer(0, 0, make(chan *Signal, 10))
+	server.MaxRequestSize = 1 << 20
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/traces", bytes.NewReader(zs.Bytes()))
+	req.Header.Set("Content-Type", ContentTypeProto)
+	req.Header.Set("Content-Encoding", "zstd")
+	rec := httptest.NewRecorder()
+	server.httpTraceHandler(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("invalid status => %d", rec.Code)
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.ServeGRPC(lis)
+	defer server.Stop()
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, err = ptraceotlp.NewGRPCClient(conn).Export(context.Background(), ptraceotlp.NewExportRequestFromTraces(td), grpc.UseCompressor("zstd"))
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("invalid Export() => %v", err)
+	}
+
+}
+
+func TestMetricSignalsEmpty(t *testing.T) {
+
+	md := pmetric.NewMetrics()
+	ms := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+	ms.AppendEmpty().SetName("empty")
+	ms.AppendEmpty().SetEmptyGauge().DataPoints().AppendEmpty().SetIntValue(1)
+	n := 0
+	MetricSignals(md, func(s *Signal) error {
+		n++
+		return nil
+	})
+	if n != 1 {
+		t.Errorf("empty metric should be skipped => %d", n)
+	}
+
+}
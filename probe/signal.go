@@ -0,0 +1,274 @@
+package probe
+
+import (
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+type KindSignal int
+
+const (
+	LOG    KindSignal = iota
+	METRIC KindSignal = iota
+	TRACE  KindSignal = iota
+)
+
+// Signal is a single span, log record or metric data point together with
+// its resource and scope. The summary and properties are built from the
+// original record on first use.
+type Signal struct {
+	Kind     KindSignal
+	Time     pcommon.Timestamp
+	Resource pcommon.Resource
+	Scope    pcommon.InstrumentationScope
+
+	// one of them is set depending on the kind, dataPoint is one of
+	// pmetric.NumberDataPoint, HistogramDataPoint,
+	// ExponentialHistogramDataPoint or SummaryDataPoint
+	Span      ptrace.Span
+	Record    plog.LogRecord
+	Metric    pmetric.Metric
+	DataPoint any
+
+	// Lint keeps violations of the semantic conventions, set before the
+	// signal is passed to the consumer
+	Lint []Violation
+
+	// Request is the received request the signal was taken from, it's
+	// shared by all signals of the request
+	Request *OTLPData
+
+	summaryOnce    sync.Once
+	summary        string
+	propertiesOnce sync.Once
+	properties     []Properties
+}
+
+func newSpanSignal(resource pcommon.Resource, scope pcommon.InstrumentationScope, span ptrace.Span) *Signal {
+	return &Signal{Kind: TRACE, Time: span.StartTimestamp(), Resource: resource, Scope: scope, Span: span}
+}
+
+func newLogSignal(resource pcommon.Resource, scope pcommon.InstrumentationScope, record plog.LogRecord) *Signal {
+	return &Signal{Kind: LOG, Time: record.Timestamp(), Resource: resource, Scope: scope, Record: record}
+}
+
+func newMetricSignal(resource pcommon.Resource, scope pcommon.InstrumentationScope, metric pmetric.Metric, dataPoint any) *Signal {
+	s := Signal{Kind: METRIC, Resource: resource, Scope: scope, Metric: metric, DataPoint: dataPoint}
+	switch dp := dataPoint.(type) {
+	case pmetric.NumberDataPoint:
+		s.Time = dp.Timestamp()
+	case pmetric.HistogramDataPoint:
+		s.Time = dp.Timestamp()
+	case pmetric.ExponentialHistogramDataPoint:
+		s.Time = dp.Timestamp()
+	case pmetric.SummaryDataPoint:
+		s.Time = dp.Timestamp()
+	}
+	return &s
+}
+
+// Summary returns one line description of the signal, signals created
+// without the record (e.g. in tests) keep the given one.
+func (s *Signal) Summary() string {
+	s.summaryOnce.Do(func() {
+		if s.summary == "" {
+			s.summary = s.buildSummary()
+		}
+	})
+	return s.summary
+}
+
+// Properties returns sections presented in the details popup.
+func (s *Signal) Properties() []Properties {
+	s.propertiesOnce.Do(func() {
+		if s.properties == nil {
+			s.properties = s.buildProperties()
+		}
+	})
+	return s.properties
+}
+
+func (s *Signal) buildSummary() string {
+	if sp, ok := TraceSpan(s); ok {
+		return fmt.Sprintf("[%v], %v, %v, %v, %v, %v, %d", sp.Kind().String(), sp.Status().Message(), sp.Name(), sp.TraceID(), sp.SpanID(), sp.ParentSpanID(), sp.Events().Len())
+	}
+	if r, ok := LogRecord(s); ok {
+		return fmt.Sprintf("%v: %v", r.SeverityText(), r.Body().AsString())
+	}
+	if !HasMetric(s) {
+		return ""
+	}
+	name := s.Metric.Name()
+	switch dp := s.DataPoint.(type) {
+	case pmetric.NumberDataPoint:
+		if s.Metric.Type() == pmetric.MetricTypeSum {
+			return fmt.Sprintf("%v=%v [%v]", name, numberValueString(dp), AttributesString(dp.Attributes()))
+		}
+		return fmt.Sprintf("%v=%v", name, numberValueString(dp))
+	case pmetric.HistogramDataPoint:
+		return histogramSummary(name, dp)
+	case pmetric.ExponentialHistogramDataPoint:
+		return expHistogramSummary(name, dp)
+	case pmetric.SummaryDataPoint:
+		return summarySummary(name, dp)
+	}
+	return name
+}
+
+// buildProperties returns sections of the record followed by the scope and
+// the resource, lint violations go first.
+func (s *Signal) buildProperties() []Properties {
+	props := s.buildRecordProperties()
+	if len(s.Lint) > 0 {
+		return append([]Properties{lintProperties(s.Lint)}, props...)
+	}
+	return props
+}
+
+func (s *Signal) buildRecordProperties() []Properties {
+	rest := make([]Properties, 0, 3)
+	if s.Scope != (pcommon.InstrumentationScope{}) {
+		rest = append(rest, scopeProperties(s.Scope))
+	}
+	if s.Resource != (pcommon.Resource{}) {
+		rest = append(rest, resourceProperties(s.Resource))
+	}
+	if sp, ok := TraceSpan(s); ok {
+		return append(spanProperties(sp), rest...)
+	}
+	if r, ok := LogRecord(s); ok {
+		return append([]Properties{logProperties(r)}, rest...)
+	}
+	if !HasMetric(s) {
+		return rest
+	}
+	rest = append([]Properties{metricProperties(s.Metric)}, rest...)
+	switch dp := s.DataPoint.(type) {
+	case pmetric.NumberDataPoint:
+		return dataPointProperties(numberProps(dp), dp.Exemplars(), rest...)
+	case pmetric.HistogramDataPoint:
+		return dataPointProperties(histogramProps(dp), dp.Exemplars(), rest...)
+	case pmetric.ExponentialHistogramDataPoint:
+		return dataPointProperties(expHistogramProps(dp), dp.Exemplars(), rest...)
+	case pmetric.SummaryDataPoint:
+		return append([]Properties{summaryProps(dp)}, rest...)
+	}
+	return rest
+}
+
+func resourceProperties(resource pcommon.Resource) Properties {
+	props := NewPropsContainer("Resource")
+	props.AddMap(resource.Attributes(), "Attributes")
+	props.AddUInt32("DroppedAttributesCount", resource.DroppedAttributesCount())
+	return props
+}
+
+func scopeProperties(scope pcommon.InstrumentationScope) Properties {
+	props := NewPropsContainer("Scope")
+	props.AddString("Name", scope.Name())
+	props.AddString("Version", scope.Version())
+	props.AddMap(scope.Attributes(), "Attributes")
+	return props
+}
+
+// singleRequest returns a new request which contains only a copy of the
+// signal, a metric keeps just its data point.
+func (s *Signal) singleRequest() OTLPData {
+	d := OTLPData{Kind: s.Kind}
+	switch s.Kind {
+	case LOG:
+		d.Logs = plog.NewLogs()
+		rl := d.Logs.ResourceLogs().AppendEmpty()
+		s.Resource.CopyTo(rl.Resource())
+		sl := rl.ScopeLogs().AppendEmpty()
+		s.Scope.CopyTo(sl.Scope())
+		s.Record.CopyTo(sl.LogRecords().AppendEmpty())
+	case METRIC:
+		d.Metrics = pmetric.NewMetrics()
+		rm := d.Metrics.ResourceMetrics().AppendEmpty()
+		s.Resource.CopyTo(rm.Resource())
+		sm := rm.ScopeMetrics().AppendEmpty()
+		s.Scope.CopyTo(sm.Scope())
+		copyMetricPoint(s, sm.Metrics().AppendEmpty())
+	case TRACE:
+		d.Traces = ptrace.NewTraces()
+		rs := d.Traces.ResourceSpans().AppendEmpty()
+		s.Resource.CopyTo(rs.Resource())
+		ss := rs.ScopeSpans().AppendEmpty()
+		s.Scope.CopyTo(ss.Scope())
+		s.Span.CopyTo(ss.Spans().AppendEmpty())
+	}
+	return d
+}
+
+// copyMetricPoint copies the metric of the signal with its data point only.
+func copyMetricPoint(s *Signal, m pmetric.Metric) {
+	m.SetName(s.Metric.Name())
+	m.SetDescription(s.Metric.Description())
+	m.SetUnit(s.Metric.Unit())
+	s.Metric.Metadata().CopyTo(m.Metadata())
+	switch dp := s.DataPoint.(type) {
+	case pmetric.NumberDataPoint:
+		if s.Metric.Type() == pmetric.MetricTypeSum {
+			sum := m.SetEmptySum()
+			sum.SetAggregationTemporality(s.Metric.Sum().AggregationTemporality())
+			sum.SetIsMonotonic(s.Metric.Sum().IsMonotonic())
+			dp.CopyTo(sum.DataPoints().AppendEmpty())
+		} else {
+			dp.CopyTo(m.SetEmptyGauge().DataPoints().AppendEmpty())
+		}
+	case pmetric.HistogramDataPoint:
+		h := m.SetEmptyHistogram()
+		h.SetAggregationTemporality(s.Metric.Histogram().AggregationTemporality())
+		dp.CopyTo(h.DataPoints().AppendEmpty())
+	case pmetric.ExponentialHistogramDataPoint:
+		h := m.SetEmptyExponentialHistogram()
+		h.SetAggregationTemporality(s.Metric.ExponentialHistogram().AggregationTemporality())
+		dp.CopyTo(h.DataPoints().AppendEmpty())
+	case pmetric.SummaryDataPoint:
+		dp.CopyTo(m.SetEmptySummary().DataPoints().AppendEmpty())
+	}
+}
+
+// LogRecord, TraceSpan and HasMetric check the kind and guard against
+// signals without the record.
+
+func LogRecord(s *Signal) (plog.LogRecord, bool) {
+	return s.Record, s.Kind == LOG && s.Record != (plog.LogRecord{})
+}
+
+func TraceSpan(s *Signal) (ptrace.Span, bool) {
+	return s.Span, s.Kind == TRACE && s.Span != (ptrace.Span{})
+}
+
+func HasMetric(s *Signal) bool {
+	return s.Kind == METRIC && s.Metric != (pmetric.Metric{})
+}
+
+func NumberDataPoint(s *Signal) (pmetric.NumberDataPoint, bool) {
+	dp, ok := s.DataPoint.(pmetric.NumberDataPoint)
+	return dp, ok && HasMetric(s)
+}
+
+// DataPointAttributes returns attributes of any type of the data point.
+func DataPointAttributes(s *Signal) (pcommon.Map, bool) {
+	if !HasMetric(s) {
+		return pcommon.Map{}, false
+	}
+	switch dp := s.DataPoint.(type) {
+	case pmetric.NumberDataPoint:
+		return dp.Attributes(), true
+	case pmetric.HistogramDataPoint:
+		return dp.Attributes(), true
+	case pmetric.ExponentialHistogramDataPoint:
+		return dp.Attributes(), true
+	case pmetric.SummaryDataPoint:
+		return dp.Attributes(), true
+	}
+	return pcommon.Map{}, false
+}
@@ -0,0 +1,102 @@
+package probe
+
+import (
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestSignalLazyBuild(t *testing.T) {
+
+	ld := plog.NewLogs()
+	rl := ld.ResourceLogs().AppendEmpty()
+	sl := rl.ScopeLogs().AppendEmpty()
+	sl.Scope().SetName("io.example.logger")
+	r := sl.LogRecords().AppendEmpty()
+	r.SetTimestamp(pcommon.Timestamp(42))
+	r.SetSeverityText("ERROR")
+	r.Body().SetStr("boom")
+
+	s := newLogSignal(rl.Resource(), sl.Scope(), r)
+	if s.summary != "" || s.properties != nil {
+		t.Errorf("summary and properties should be built on first use")
+	}
+	if s.Time != 42 || s.Summary() != "ERROR: boom" {
+		t.Errorf("invalid signal => %v, %v", s.Time, s.Summary())
+	}
+	names := []string{}
+	for _, p := range s.Properties() {
+		names = append(names, p.Name())
+	}
+	if len(names) != 3 || names[0] != "Record" || names[1] != "Scope" || names[2] != "Resource" {
+		t.Errorf("invalid sections => %v", names)
+	}
+	if !containsProp(s.Properties()[1].Get(), "Name", "io.example.logger") {
+		t.Errorf("invalid scope section => %v", s.Properties()[1].Get())
+	}
+
+	// signals without the record keep the given summary
+	if s := (&Signal{summary: "test"}); s.Summary() != "test" || len(s.Properties()) != 0 {
+		t.Errorf("invalid signal without record => %v, %v", s.Summary(), s.Properties())
+	}
+
+}
+
+func TestMetricSignal(t *testing.T) {
+
+	m := pmetric.NewMetric()
+	m.SetName("requests")
+	m.SetEmptySum().SetIsMonotonic(true)
+	dp := m.Sum().DataPoints().AppendEmpty()
+	dp.SetTimestamp(pcommon.Timestamp(7))
+	dp.SetIntValue(3)
+	dp.Attributes().PutStr("status", "200")
+	dp.Attributes().PutStr("method", "GET")
+
+	s := newMetricSignal(pcommon.NewResource(), pcommon.NewInstrumentationScope(), m, dp)
+	if s.Time != 7 || s.Summary() != "requests=3 [method=GET,status=200]" {
+		t.Errorf("invalid signal => %v, %v", s.Time, s.Summary())
+	}
+	props := s.Properties()
+	if props[0].Name() != "DataPoint" || !containsProp(props[0].Get(), "Value", "3") ||
+		props[1].Name() != "Metric" || !containsProp(props[1].Get(), "IsMonotonic", "True") {
+		t.Errorf("invalid properties => %v", props)
+	}
+	if attrs, ok := DataPointAttributes(s); !ok || attrs.Len() != 2 {
+		t.Errorf("invalid dataPointAttributes() => %v, %v", attrs, ok)
+	}
+
+}
+
+func TestSignalConcurrentProperties(t *testing.T) {
+
+	ld := plog.NewLogs()
+	rl := ld.ResourceLogs().AppendEmpty()
+	sl := rl.ScopeLogs().AppendEmpty()
+	r := sl.LogRecords().AppendEmpty()
+	for _, k := range []string{"b", "a", "d", "c"} {
+		r.Attributes().PutStr(k, k)
+	}
+	s := newLogSignal(rl.Resource(), sl.Scope(), r)
+	s.Properties()
+
+	// properties are shared, e.g. by the browser, the output and the API
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, p := range s.Properties() {
+				p.Get()
+			}
+		}()
+	}
+	wg.Wait()
+	if attr := s.Properties()[0].Get(); attr[0][0] != "Attributes.a" || attr[3][0] != "Attributes.d" {
+		t.Errorf("invalid order of properties => %v", attr)
+	}
+
+}
@@ -0,0 +1,57 @@
+package probe
+
+import (
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// spanProperties returns sections describing the span: the span itself,
+// its status and a section per event and link.
+func spanProperties(sp ptrace.Span) []Properties {
+	spanProps := NewPropsContainer("Span")
+	spanProps.AddMap(sp.Attributes(), "Attributes")
+	spanProps.AddString("Name", sp.Name())
+	spanProps.AddString("Kind", sp.Kind().String())
+	spanProps.AddString("TraceId", sp.TraceID().String())
+	spanProps.AddString("SpanId", sp.SpanID().String())
+	spanProps.AddString("ParentSpanId", sp.ParentSpanID().String())
+	spanProps.AddString("TraceState", sp.TraceState().AsRaw())
+	spanProps.AddUInt32("Flags", sp.Flags())
+	spanProps.AddTimestamp("StartTimestamp", sp.StartTimestamp())
+	spanProps.AddTimestamp("EndTimestamp", sp.EndTimestamp())
+	spanProps.AddString("Duration", sp.EndTimestamp().AsTime().Sub(sp.StartTimestamp().AsTime()).String())
+	spanProps.AddUInt32("DroppedAttributesCount", sp.DroppedAttributesCount())
+	spanProps.AddUInt32("DroppedEventsCount", sp.DroppedEventsCount())
+	spanProps.AddUInt32("DroppedLinksCount", sp.DroppedLinksCount())
+
+	statusProps := NewPropsContainer("Status")
+	statusProps.AddString("Code", sp.Status().Code().String())
+	statusProps.AddString("Message", sp.Status().Message())
+
+	props := []Properties{spanProps, statusProps}
+
+	events := sp.Events()
+	for i := 0; i < events.Len(); i++ {
+		ev := events.At(i)
+		evProps := NewPropsContainer(indexedName("Event", i, events.Len()) + " " + ev.Name())
+		evProps.AddMap(ev.Attributes(), "Attributes")
+		evProps.AddString("Name", ev.Name())
+		evProps.AddTimestamp("Timestamp", ev.Timestamp())
+		evProps.AddUInt32("DroppedAttributesCount", ev.DroppedAttributesCount())
+		props = append(props, evProps)
+	}
+
+	links := sp.Links()
+	for i := 0; i < links.Len(); i++ {
+		l := links.At(i)
+		linkProps := NewPropsContainer(indexedName("Link", i, links.Len()))
+		linkProps.AddMap(l.Attributes(), "Attributes")
+		linkProps.AddString("TraceId", l.TraceID().String())
+		linkProps.AddString("SpanId", l.SpanID().String())
+		linkProps.AddString("TraceState", l.TraceState().AsRaw())
+		linkProps.AddUInt32("Flags", l.Flags())
+		linkProps.AddUInt32("DroppedAttributesCount", l.DroppedAttributesCount())
+		props = append(props, linkProps)
+	}
+
+	return props
+}
@@ -0,0 +1,58 @@
+package probe
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func TestProcessTracesDetails(t *testing.T) {
+
+	preq := newTestTraces()
+	sp := preq.Traces().ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	sp.Status().SetCode(ptrace.StatusCodeError)
+	sp.Status().SetMessage("boom")
+	sp.SetDroppedEventsCount(3)
+	ev := sp.Events().AppendEmpty()
+	ev.SetName("exception")
+	ev.Attributes().PutStr("exception.stacktrace", "main.go:42")
+	link := sp.Links().AppendEmpty()
+	link.SetTraceID([16]byte{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1})
+
+	ch := make(chan *Signal, 10)
+	server := NewServer(0, 0, ch)
+	td := preq.Traces()
+	if err := server.processTraces(context.Background(), &td); err != nil {
+		t.Fatal(err)
+	}
+	s := <-ch
+
+	names := []string{}
+	for _, p := range s.Properties() {
+		names = append(names, p.Name())
+	}
+	expected := []string{"Span", "Status", "Event[0] exception", "Link[0]", "Scope", "Resource"}
+	if len(names) != len(expected) {
+		t.Fatalf("invalid sections => %v", names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("invalid sections => %v", names)
+		}
+	}
+
+	if !containsProp(s.Properties()[0].Get(), "DroppedEventsCount", "3") || !containsProp(s.Properties()[0].Get(), "Kind", "Unspecified") {
+		t.Errorf("invalid span section => %v", s.Properties()[0].Get())
+	}
+	if !containsProp(s.Properties()[1].Get(), "Code", "Error") || !containsProp(s.Properties()[1].Get(), "Message", "boom") {
+		t.Errorf("invalid status section => %v", s.Properties()[1].Get())
+	}
+	if !containsProp(s.Properties()[2].Get(), "Attributes.exception.stacktrace", "main.go:42") {
+		t.Errorf("invalid event section => %v", s.Properties()[2].Get())
+	}
+	if !containsProp(s.Properties()[3].Get(), "TraceId", "100f0e0d0c0b0a090807060504030201") {
+		t.Errorf("invalid link section => %v", s.Properties()[3].Get())
+	}
+
+}
@@ -0,0 +1,728 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tomplus/otlprobe/probe"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+// Query is a compiled filter expression, e.g.
+//
+//	kind:log severity>=WARN resource.service.name=checkout
+//	span.duration>500ms or (attr.http.status_code=~5.. and not name:health)
+//
+// Terms are joined with and (implicit), or, not and parentheses. A term is
+// field, operator and value, words without an operator are looked up in
+// the summary, so a plain text works as a filter as well.
+type Query struct {
+	text string
+	root queryNode
+}
+
+type queryNode interface {
+	match(s *probe.Signal) bool
+}
+
+type andNode struct {
+	left  queryNode
+	right queryNode
+}
+
+type orNode struct {
+	left  queryNode
+	right queryNode
+}
+
+type notNode struct {
+	node queryNode
+}
+
+// textNode matches the text in the summary.
+type textNode struct {
+	text string
+}
+
+type compareNode struct {
+	field   queryField
+	op      string
+	value   string
+	number  float64
+	numeric bool
+	re      *regexp.Regexp
+}
+
+// fieldValue is a value of the signal field used in comparisons.
+type fieldValue struct {
+	text    string
+	number  float64
+	numeric bool
+}
+
+type queryField struct {
+	get func(s *probe.Signal) (fieldValue, bool)
+	// parse converts the value from the query to number, e.g. WARN => 13
+	parse func(text string) (float64, error)
+	// normalize checks and converts the value from the query, e.g. logs => log
+	normalize func(text string) (string, error)
+}
+
+type queryTokenKind int
+
+const (
+	tokenEOF queryTokenKind = iota
+	tokenWord
+	tokenLParen
+	tokenRParen
+	tokenAnd
+	tokenOr
+	tokenNot
+)
+
+type queryToken struct {
+	kind queryTokenKind
+	text string
+	pos  int
+	// quoteAt is the offset of the first quoted part of the word, -1 if none
+	quoteAt int
+}
+
+// queryOperators are ordered, so longer operators are matched first.
+var queryOperators = []string{"=~", "!~", "!=", ">=", "<=", ":", "=", "<", ">"}
+
+var queryFields = map[string]queryField{
+	"kind":          {get: kindField, normalize: normalizeKind},
+	"name":          {get: nameField},
+	"summary":       {get: func(s *probe.Signal) (fieldValue, bool) { return fieldValue{text: s.Summary()}, true }},
+	"severity":      {get: severityField, parse: parseSeverity},
+	"body":          {get: bodyField},
+	"duration":      {get: durationField, parse: parseDurationValue},
+	"span.duration": {get: durationField, parse: parseDurationValue},
+	"span.name":     {get: nameField},
+	"span.kind":     {get: spanKindField, normalize: normalizeLower},
+	"status":        {get: statusField, normalize: normalizeLower},
+	"span.status":   {get: statusField, normalize: normalizeLower},
+	"trace_id":      {get: traceIDField},
+	"span_id":       {get: spanIDField},
+	"parent_id":     {get: parentIDField},
+	"value":         {get: valueField},
+	"scope.name":    {get: scopeNameField},
+	"scope.version": {get: scopeVersionField},
+}
+
+var kindNames = map[string]string{
+	"log": "log", "logs": "log",
+	"metric": "metric", "metrics": "metric",
+	"trace": "trace", "traces": "trace", "span": "trace", "spans": "trace",
+}
+
+// severityNames are the base severity numbers, e.g. WARN2 is 14.
+var severityNames = map[string]plog.SeverityNumber{
+	"TRACE":   plog.SeverityNumberTrace,
+	"DEBUG":   plog.SeverityNumberDebug,
+	"INFO":    plog.SeverityNumberInfo,
+	"WARN":    plog.SeverityNumberWarn,
+	"WARNING": plog.SeverityNumberWarn,
+	"ERROR":   plog.SeverityNumberError,
+	"FATAL":   plog.SeverityNumberFatal,
+}
+
+// parseQuery compiles the query, an empty query matches everything.
+func parseQuery(text string) (*Query, error) {
+	tokens, err := lexQuery(text)
+	if err != nil {
+		return nil, err
+	}
+	q := Query{text: text}
+	if len(tokens) == 1 {
+		return &q, nil
+	}
+	p := queryParser{tokens: tokens}
+	q.root, err = p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+	}
+	return &q, nil
+}
+
+func (q *Query) String() string {
+	if q == nil {
+		return ""
+	}
+	return q.text
+}
+
+func (q *Query) match(s *probe.Signal) bool {
+	if q == nil || q.root == nil {
+		return true
+	}
+	return q.root.match(s)
+}
+
+// terms returns texts searched in the summary (not negated ones), they
+// are highlighted in the browser.
+func (q *Query) terms() []string {
+	res := make([]string, 0)
+	var walk func(n queryNode)
+	walk = func(n queryNode) {
+		switch n := n.(type) {
+		case *andNode:
+			walk(n.left)
+			walk(n.right)
+		case *orNode:
+			walk(n.left)
+			walk(n.right)
+		case *textNode:
+			res = append(res, n.text)
+		}
+	}
+	if q != nil && q.root != nil {
+		walk(q.root)
+	}
+	return res
+}
+
+func lexQuery(text string) ([]queryToken, error) {
+	tokens := make([]queryToken, 0)
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(':
+			tokens = append(tokens, queryToken{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, queryToken{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case strings.HasPrefix(text[i:], "&&"):
+			tokens = append(tokens, queryToken{kind: tokenAnd, text: "&&", pos: i})
+			i += 2
+		case strings.HasPrefix(text[i:], "||"):
+			tokens = append(tokens, queryToken{kind: tokenOr, text: "||", pos: i})
+			i += 2
+		case c == '!' && !strings.HasPrefix(text[i:], "!=") && !strings.HasPrefix(text[i:], "!~"):
+			tokens = append(tokens, queryToken{kind: tokenNot, text: "!", pos: i})
+			i++
+		default:
+			t, err := lexWord(text, i)
+			if err != nil {
+				return nil, err
+			}
+			i += len(t.text)
+			t.text, t.quoteAt = unquoteWord(t.text)
+			switch strings.ToLower(t.text) {
+			case "and":
+				t.kind = tokenAnd
+			case "or":
+				t.kind = tokenOr
+			case "not":
+				t.kind = tokenNot
+			}
+			if t.quoteAt != -1 {
+				t.kind = tokenWord
+			}
+			tokens = append(tokens, t)
+		}
+	}
+	return append(tokens, queryToken{kind: tokenEOF, pos: len(text)}), nil
+}
+
+// lexWord returns the raw word which starts at the position. The word ends
+// with a space or unbalanced parenthesis, so regular expressions like
+// name=~(get|post).* don't have to be quoted.
+func lexWord(text string, start int) (queryToken, error) {
+	depth := 0
+	i := start
+loop:
+	for i < len(text) {
+		switch text[i] {
+		case '"':
+			end := i + 1
+			for end < len(text) && text[end] != '"' {
+				if text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(text) {
+				return queryToken{}, fmt.Errorf("unterminated quote at %d", i)
+			}
+			i = end
+		case ' ', '\t':
+			if depth == 0 {
+				break loop
+			}
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				break loop
+			}
+			depth--
+		}
+		i++
+	}
+	return queryToken{kind: tokenWord, text: text[start:i], pos: start}, nil
+}
+
+// unquoteWord removes quotes and returns offset of the first quoted part.
+func unquoteWord(raw string) (string, int) {
+	var sb strings.Builder
+	quoteAt := -1
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '"' {
+			sb.WriteByte(raw[i])
+			continue
+		}
+		if quoteAt == -1 {
+			quoteAt = sb.Len()
+		}
+		for i++; i < len(raw) && raw[i] != '"'; i++ {
+			if raw[i] == '\\' && i+1 < len(raw) {
+				i++
+			}
+			sb.WriteByte(raw[i])
+		}
+	}
+	return sb.String(), quoteAt
+}
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() queryToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek().kind {
+		case tokenEOF, tokenRParen, tokenOr:
+			return left, nil
+		case tokenAnd:
+			p.next()
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+}
+
+func (p *queryParser) parseUnary() (queryNode, error) {
+	if p.peek().kind == tokenNot {
+		p.next()
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{node: node}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (queryNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if r := p.next(); r.kind != tokenRParen {
+			return nil, fmt.Errorf("missing ) at %d", r.pos)
+		}
+		return node, nil
+	case tokenWord:
+		return parseTerm(t)
+	case tokenEOF:
+		return nil, fmt.Errorf("unexpected end of query")
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
+
+var fieldName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.\-]*$`)
+
+// parseTerm splits the word to field, operator and value. Operators in
+// the quoted part are not taken into account.
+func parseTerm(t queryToken) (queryNode, error) {
+	limit := len(t.text)
+	if t.quoteAt != -1 {
+		limit = t.quoteAt
+	}
+	idx := strings.IndexAny(t.text[:limit], ":=!<>")
+	if idx == -1 {
+		return &textNode{text: t.text}, nil
+	}
+	// words like URLs (http://host, /api?x=1) are searched as text, other
+	// names are fields, so a misspelled one is reported
+	if idx > 0 && (!fieldName.MatchString(t.text[:idx]) || strings.HasPrefix(t.text[idx:], "://")) {
+		return &textNode{text: t.text}, nil
+	}
+	for _, op := range queryOperators {
+		if strings.HasPrefix(t.text[idx:], op) {
+			if idx == 0 {
+				return nil, fmt.Errorf("missing field name at %d", t.pos)
+			}
+			return newCompareNode(t.text[:idx], op, t.text[idx+len(op):], t.pos)
+		}
+	}
+	return &textNode{text: t.text}, nil
+}
+
+func newCompareNode(name string, op string, value string, pos int) (queryNode, error) {
+	field, ok := lookupField(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q at %d", name, pos)
+	}
+	if field.normalize != nil {
+		v, err := field.normalize(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of %s at %d: %w", name, pos, err)
+		}
+		value = v
+	}
+	node := compareNode{field: field, op: op, value: value}
+	switch op {
+	case "=~", "!~":
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid regexp at %d: %w", pos, err)
+		}
+		node.re = re
+	default:
+		parse := field.parse
+		if parse == nil {
+			parse = func(text string) (float64, error) {
+				return strconv.ParseFloat(text, 64)
+			}
+		}
+		n, err := parse(value)
+		if err == nil {
+			node.number, node.numeric = n, true
+		} else if op != ":" && op != "=" && op != "!=" {
+			return nil, fmt.Errorf("invalid value of %s at %d: %w", name, pos, err)
+		}
+	}
+	return &node, nil
+}
+
+// lookupField returns the field, attributes are prefixed with attr. or resource.
+func lookupField(name string) (queryField, bool) {
+	if key, ok := strings.CutPrefix(name, "attr."); ok && key != "" {
+		return attributeField(signalAttributes, key), true
+	}
+	if key, ok := strings.CutPrefix(name, "resource."); ok && key != "" {
+		return attributeField(resourceAttributes, key), true
+	}
+	field, ok := queryFields[strings.ToLower(name)]
+	return field, ok
+}
+
+func (n *andNode) match(s *probe.Signal) bool {
+	return n.left.match(s) && n.right.match(s)
+}
+
+func (n *orNode) match(s *probe.Signal) bool {
+	return n.left.match(s) || n.right.match(s)
+}
+
+func (n *notNode) match(s *probe.Signal) bool {
+	return !n.node.match(s)
+}
+
+func (n *textNode) match(s *probe.Signal) bool {
+	return strings.Contains(s.Summary(), n.text)
+}
+
+// match compares the field of the signal, signals without the field match
+// negative operators only (!= and !~).
+func (n *compareNode) match(s *probe.Signal) bool {
+	v, ok := n.field.get(s)
+	if !ok {
+		return n.op == "!=" || n.op == "!~"
+	}
+	switch n.op {
+	case ":":
+		if n.numeric && v.numeric {
+			return v.number == n.number
+		}
+		return strings.Contains(strings.ToLower(v.text), strings.ToLower(n.value))
+	case "=":
+		return n.equal(v)
+	case "!=":
+		return !n.equal(v)
+	case "=~":
+		return n.re.MatchString(v.text)
+	case "!~":
+		return !n.re.MatchString(v.text)
+	}
+	if !v.numeric {
+		return false
+	}
+	switch n.op {
+	case ">":
+		return v.number > n.number
+	case ">=":
+		return v.number >= n.number
+	case "<":
+		return v.number < n.number
+	case "<=":
+		return v.number <= n.number
+	}
+	return false
+}
+
+func (n *compareNode) equal(v fieldValue) bool {
+	if n.numeric && v.numeric {
+		return v.number == n.number
+	}
+	return v.text == n.value
+}
+
+func normalizeKind(text string) (string, error) {
+	kind, ok := kindNames[strings.ToLower(text)]
+	if !ok {
+		return "", fmt.Errorf("expected log, metric or trace, got %q", text)
+	}
+	return kind, nil
+}
+
+func normalizeLower(text string) (string, error) {
+	return strings.ToLower(text), nil
+}
+
+// parseSeverity accepts severity number or name, e.g. 13, WARN, warn2.
+func parseSeverity(text string) (float64, error) {
+	if n, err := strconv.Atoi(text); err == nil {
+		return float64(n), nil
+	}
+	name := strings.ToUpper(text)
+	offset := 0
+	if l := len(name); l > 1 && name[l-1] >= '1' && name[l-1] <= '4' {
+		offset = int(name[l-1] - '1')
+		name = name[:l-1]
+	}
+	n, ok := severityNames[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown severity %q", text)
+	}
+	return float64(int(n) + offset), nil
+}
+
+func parseDurationValue(text string) (float64, error) {
+	d, err := time.ParseDuration(text)
+	return float64(d), err
+}
+
+func kindName(kind probe.KindSignal) string {
+	switch kind {
+	case probe.LOG:
+		return "log"
+	case probe.METRIC:
+		return "metric"
+	case probe.TRACE:
+		return "trace"
+	}
+	return ""
+}
+
+func kindField(s *probe.Signal) (fieldValue, bool) {
+	return fieldValue{text: kindName(s.Kind)}, true
+}
+
+func nameField(s *probe.Signal) (fieldValue, bool) {
+	if sp, ok := probe.TraceSpan(s); ok {
+		return fieldValue{text: sp.Name()}, true
+	}
+	if probe.HasMetric(s) {
+		return fieldValue{text: s.Metric.Name()}, true
+	}
+	return fieldValue{}, false
+}
+
+func scopeNameField(s *probe.Signal) (fieldValue, bool) {
+	if s.Scope == (pcommon.InstrumentationScope{}) {
+		return fieldValue{}, false
+	}
+	return fieldValue{text: s.Scope.Name()}, true
+}
+
+func scopeVersionField(s *probe.Signal) (fieldValue, bool) {
+	if s.Scope == (pcommon.InstrumentationScope{}) {
+		return fieldValue{}, false
+	}
+	return fieldValue{text: s.Scope.Version()}, true
+}
+
+func severityField(s *probe.Signal) (fieldValue, bool) {
+	r, ok := probe.LogRecord(s)
+	if !ok {
+		return fieldValue{}, false
+	}
+	number := r.SeverityNumber()
+	text := r.SeverityText()
+	if number == plog.SeverityNumberUnspecified && text != "" {
+		if n, err := parseSeverity(text); err == nil {
+			number = plog.SeverityNumber(n)
+		}
+	}
+	if text == "" {
+		text = strings.ToUpper(number.String())
+	}
+	return fieldValue{text: text, number: float64(number), numeric: number != plog.SeverityNumberUnspecified}, true
+}
+
+func bodyField(s *probe.Signal) (fieldValue, bool) {
+	r, ok := probe.LogRecord(s)
+	if !ok {
+		return fieldValue{}, false
+	}
+	return fieldValue{text: r.Body().AsString()}, true
+}
+
+func durationField(s *probe.Signal) (fieldValue, bool) {
+	sp, ok := probe.TraceSpan(s)
+	if !ok {
+		return fieldValue{}, false
+	}
+	d := time.Duration(sp.EndTimestamp() - sp.StartTimestamp())
+	return fieldValue{text: d.String(), number: float64(d), numeric: true}, true
+}
+
+func spanKindField(s *probe.Signal) (fieldValue, bool) {
+	sp, ok := probe.TraceSpan(s)
+	if !ok {
+		return fieldValue{}, false
+	}
+	return fieldValue{text: strings.ToLower(sp.Kind().String())}, true
+}
+
+func statusField(s *probe.Signal) (fieldValue, bool) {
+	sp, ok := probe.TraceSpan(s)
+	if !ok {
+		return fieldValue{}, false
+	}
+	return fieldValue{text: strings.ToLower(sp.Status().Code().String())}, true
+}
+
+func traceIDField(s *probe.Signal) (fieldValue, bool) {
+	if sp, ok := probe.TraceSpan(s); ok {
+		return fieldValue{text: sp.TraceID().String()}, true
+	}
+	if r, ok := probe.LogRecord(s); ok {
+		return fieldValue{text: r.TraceID().String()}, true
+	}
+	return fieldValue{}, false
+}
+
+func spanIDField(s *probe.Signal) (fieldValue, bool) {
+	if sp, ok := probe.TraceSpan(s); ok {
+		return fieldValue{text: sp.SpanID().String()}, true
+	}
+	if r, ok := probe.LogRecord(s); ok {
+		return fieldValue{text: r.SpanID().String()}, true
+	}
+	return fieldValue{}, false
+}
+
+func parentIDField(s *probe.Signal) (fieldValue, bool) {
+	if sp, ok := probe.TraceSpan(s); ok {
+		return fieldValue{text: sp.ParentSpanID().String()}, true
+	}
+	return fieldValue{}, false
+}
+
+func valueField(s *probe.Signal) (fieldValue, bool) {
+	dp, ok := probe.NumberDataPoint(s)
+	if !ok {
+		return fieldValue{}, false
+	}
+	v, ok := numberValue(dp)
+	if !ok {
+		return fieldValue{}, false
+	}
+	return fieldValue{text: probe.FormatFloat(v), number: v, numeric: true}, true
+}
+
+func signalAttributes(s *probe.Signal) (pcommon.Map, bool) {
+	if sp, ok := probe.TraceSpan(s); ok {
+		return sp.Attributes(), true
+	}
+	if r, ok := probe.LogRecord(s); ok {
+		return r.Attributes(), true
+	}
+	return probe.DataPointAttributes(s)
+}
+
+func resourceAttributes(s *probe.Signal) (pcommon.Map, bool) {
+	if s.Resource == (pcommon.Resource{}) {
+		return pcommon.Map{}, false
+	}
+	return s.Resource.Attributes(), true
+}
+
+func attributeField(attributes func(s *probe.Signal) (pcommon.Map, bool), key string) queryField {
+	return queryField{get: func(s *probe.Signal) (fieldValue, bool) {
+		m, ok := attributes(s)
+		if !ok {
+			return fieldValue{}, false
+		}
+		v, ok := m.Get(key)
+		if !ok {
+			return fieldValue{}, false
+		}
+		fv := fieldValue{text: v.AsString()}
+		switch v.Type() {
+		case pcommon.ValueTypeInt:
+			fv.number, fv.numeric = float64(v.Int()), true
+		case pcommon.ValueTypeDouble:
+			fv.number, fv.numeric = v.Double(), true
+		case pcommon.ValueTypeStr:
+			if n, err := strconv.ParseFloat(v.Str(), 64); err == nil {
+				fv.number, fv.numeric = n, true
+			}
+		}
+		return fv, true
+	}}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tomplus/otlprobe/probe"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// newTestSignals returns a warning log from checkout, an info log from cart,
+// a slow server span with error, a health check span and a gauge.
+func newTestSignals(t *testing.T) []*probe.Signal {
+	ch := make(chan *probe.Signal, 10)
+	server := probe.NewServer(0, 0, ch)
+
+	ld := plog.NewLogs()
+	rl := ld.ResourceLogs().AppendEmpty()
+	rl.Resource().Attributes().PutStr("service.name", "checkout")
+	lr := rl.ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	lr.SetSeverityNumber(plog.SeverityNumberWarn)
+	lr.SetSeverityText("WARN")
+	lr.Body().SetStr("payment delayed")
+	rl = ld.ResourceLogs().AppendEmpty()
+	rl.Resource().Attributes().PutStr("service.name", "cart")
+	lr = rl.ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	lr.SetSeverityText("info")
+	lr.Body().SetStr("item added")
+	if err := server.Process(context.Background(), probe.OTLPData{Kind: probe.LOG, Logs: ld}); err != nil {
+		t.Fatal(err)
+	}
+
+	td := ptrace.NewTraces()
+	spans := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+	sp := spans.AppendEmpty()
+	sp.SetName("POST /orders")
+	sp.SetKind(ptrace.SpanKindServer)
+	sp.SetStartTimestamp(0)
+	sp.SetEndTimestamp(pcommon.Timestamp(time.Second))
+	sp.Status().SetCode(ptrace.StatusCodeError)
+	sp.Attributes().PutInt("http.status_code", 503)
+	sp = spans.AppendEmpty()
+	sp.SetName("GET /health")
+	sp.SetEndTimestamp(pcommon.Timestamp(time.Millisecond))
+	sp.Attributes().PutStr("http.status_code", "200")
+	if err := server.Process(context.Background(), probe.OTLPData{Kind: probe.TRACE, Traces: td}); err != nil {
+		t.Fatal(err)
+	}
+
+	md := pmetric.NewMetrics()
+	m := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+	m.SetName("queue.size")
+	m.SetEmptyGauge().DataPoints().AppendEmpty().SetIntValue(42)
+	if err := server.Process(context.Background(), probe.OTLPData{Kind: probe.METRIC, Metrics: md}); err != nil {
+		t.Fatal(err)
+	}
+
+	close(ch)
+	signals := make([]*probe.Signal, 0)
+	for s := range ch {
+		signals = append(signals, s)
+	}
+	return signals
+}
+
+func TestQueryMatch(t *testing.T) {
+
+	signals := newTestSignals(t)
+	if len(signals) != 5 {
+		t.Fatalf("invalid number of signals => %d", len(signals))
+	}
+
+	tests := []struct {
+		query    string
+		expected []bool
+	}{
+		{"", []bool{true, true, true, true, true}},
+		{"kind:log", []bool{true, true, false, false, false}},
+		{"kind:spans", []bool{false, false, true, true, false}},
+		{"kind:log severity>=WARN resource.service.name=checkout", []bool{true, false, false, false, false}},
+		{"severity=info", []bool{false, true, false, false, false}},
+		{"span.duration>500ms", []bool{false, false, true, false, false}},
+		{"attr.http.status_code=~5..", []bool{false, false, true, false, false}},
+		{"attr.http.status_code<300", []bool{false, false, false, true, false}},
+		{"kind:trace not name:health", []bool{false, false, true, false, false}},
+		{"kind:trace and !(status=error)", []bool{false, false, false, true, false}},
+		{"span.kind=SERVER || value>=42", []bool{false, false, true, false, true}},
+		{"name!=queue.size", []bool{true, true, true, true, false}},
+		{`body:"item added" or name="GET /health"`, []bool{false, true, false, true, false}},
+		{"delayed", []bool{true, false, false, false, false}},
+		{`"kind:log"`, []bool{false, false, false, false, false}},
+		{"name=~(get|post).* or name=~(GET|POST).*", []bool{false, false, true, true, false}},
+	}
+	for _, tc := range tests {
+		q, err := parseQuery(tc.query)
+		if err != nil {
+			t.Errorf("invalid query %q => %v", tc.query, err)
+			continue
+		}
+		for i, s := range signals {
+			if q.match(s) != tc.expected[i] {
+				t.Errorf("invalid match of %q for %v => %v", tc.query, s.Summary(), q.match(s))
+			}
+		}
+	}
+
+}
+
+func TestQueryErrors(t *testing.T) {
+
+	for _, query := range []string{
+		"kind:foo",
+		"severity>=LOUD",
+		"span.duration>5 minutes",
+		"(kind:log",
+		"kind:log)",
+		"name=~[",
+		`name="foo`,
+		"=value",
+		"sevrity>=WARN",
+		"x=1",
+		"kind:log or",
+		"not",
+	} {
+		if _, err := parseQuery(query); err == nil {
+			t.Errorf("query %q should fail", query)
+		}
+	}
+	if _, err := parseQuery("sevrity>=WARN"); err == nil || !strings.Contains(err.Error(), `unknown field "sevrity"`) {
+		t.Errorf("misspelled field should be reported => %v", err)
+	}
+
+}
+
+func TestQueryText(t *testing.T) {
+
+	ld := plog.NewLogs()
+	ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr("GET http://host:8080/api?x=1 took a<b")
+	var s *probe.Signal
+	probe.LogSignals(ld, func(sig *probe.Signal) error {
+		s = sig
+		return nil
+	})
+
+	// words with operators which aren't field names are searched as text
+	for _, query := range []string{"/api?x=1", "http://host", `"a<b"`, `"x=1"`, "GET http://host:8080/api?x=1", `not "http.method=POST"`} {
+		q, err := parseQuery(query)
+		if err != nil {
+			t.Errorf("invalid query %q => %v", query, err)
+			continue
+		}
+		if !q.match(s) {
+			t.Errorf("query %q should match %q", query, s.Summary())
+		}
+	}
+	q, _ := parseQuery("http://other")
+	if q.match(s) {
+		t.Errorf("query http://other shouldn't match %q", s.Summary())
+	}
+
+}
+
+func TestQueryTerms(t *testing.T) {
+
+	q, err := parseQuery(`error kind:log not debug "GET /"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	terms := q.terms()
+	if len(terms) != 2 || terms[0] != "error" || terms[1] != "GET /" {
+		t.Errorf("invalid terms => %v", terms)
+	}
+
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tomplus/otlprobe/probe"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+)
+
+// Replayer exports requests read from files again. Requests are sent one by
+// one keeping the original intervals divided by speed, zero speed sends them
+// as fast as possible.
+type Replayer struct {
+	exporter Exporter
+	speed    float64
+	shift    bool
+	timeout  time.Duration
+
+	first    time.Time // time of the first request
+	start    time.Time // when the first request was sent
+	requests int
+	signals  int
+	failed   int
+}
+
+func newReplayer(exporter Exporter, speed float64, shift bool, timeout time.Duration) *Replayer {
+	r := Replayer{
+		exporter: exporter,
+		speed:    speed,
+		shift:    shift,
+		timeout:  timeout,
+	}
+	return &r
+}
+
+// replay waits until the request is due and exports it. With shift the
+// timestamps of all requests are moved by the same offset, so the first
+// request looks like it was created now and the intervals between signals,
+// e.g. spans of a trace sent in different requests, are kept.
+func (r *Replayer) replay(ctx context.Context, d probe.OTLPData) error {
+	t := requestTime(d)
+	now := time.Now()
+	if r.first.IsZero() && !t.IsZero() {
+		// the schedule and the shift start with the first request with a time
+		r.first, r.start = t, now
+	}
+	if r.speed > 0 && !t.IsZero() {
+		due := r.start.Add(time.Duration(float64(t.Sub(r.first)) / r.speed))
+		if wait := due.Sub(now); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			now = time.Now()
+		}
+	}
+	if r.shift && !t.IsZero() {
+		shiftTimestamps(d, r.start.Sub(r.first))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	if err := exportData(ctx, r.exporter, d); err != nil {
+		r.failed++
+		return err
+	}
+	r.requests++
+	d.Signals(func(s *probe.Signal) error {
+		r.signals++
+		return nil
+	})
+	return nil
+}
+
+// requestTime returns the receive time of captured requests, otherwise
+// the latest timestamp of the signals, when the request was likely sent.
+func requestTime(d probe.OTLPData) time.Time {
+	if !d.Received.IsZero() {
+		return d.Received
+	}
+	latest := pcommon.Timestamp(0)
+	d.Signals(func(s *probe.Signal) error {
+		latest = max(latest, s.Time)
+		if sp, ok := probe.TraceSpan(s); ok {
+			latest = max(latest, sp.EndTimestamp())
+		}
+		if lr, ok := probe.LogRecord(s); ok {
+			latest = max(latest, lr.ObservedTimestamp())
+		}
+		return nil
+	})
+	if latest == 0 {
+		return time.Time{}
+	}
+	return latest.AsTime()
+}
+
+// shiftTimestamps moves all timestamps of the signals by delta, timestamps
+// which are not set are kept.
+func shiftTimestamps(d probe.OTLPData, delta time.Duration) {
+	shift := func(ts pcommon.Timestamp) pcommon.Timestamp {
+		if ts == 0 {
+			return 0
+		}
+		return pcommon.Timestamp(int64(ts) + int64(delta))
+	}
+	shiftExemplars := func(exs pmetric.ExemplarSlice) {
+		for i := 0; i < exs.Len(); i++ {
+			exs.At(i).SetTimestamp(shift(exs.At(i).Timestamp()))
+		}
+	}
+	d.Signals(func(s *probe.Signal) error {
+		if sp, ok := probe.TraceSpan(s); ok {
+			sp.SetStartTimestamp(shift(sp.StartTimestamp()))
+			sp.SetEndTimestamp(shift(sp.EndTimestamp()))
+			for i := 0; i < sp.Events().Len(); i++ {
+				sp.Events().At(i).SetTimestamp(shift(sp.Events().At(i).Timestamp()))
+			}
+		}
+		if lr, ok := probe.LogRecord(s); ok {
+			lr.SetTimestamp(shift(lr.Timestamp()))
+			lr.SetObservedTimestamp(shift(lr.ObservedTimestamp()))
+		}
+		switch dp := s.DataPoint.(type) {
+		case pmetric.NumberDataPoint:
+			dp.SetStartTimestamp(shift(dp.StartTimestamp()))
+			dp.SetTimestamp(shift(dp.Timestamp()))
+			shiftExemplars(dp.Exemplars())
+		case pmetric.HistogramDataPoint:
+			dp.SetStartTimestamp(shift(dp.StartTimestamp()))
+			dp.SetTimestamp(shift(dp.Timestamp()))
+			shiftExemplars(dp.Exemplars())
+		case pmetric.ExponentialHistogramDataPoint:
+			dp.SetStartTimestamp(shift(dp.StartTimestamp()))
+			dp.SetTimestamp(shift(dp.Timestamp()))
+			shiftExemplars(dp.Exemplars())
+		case pmetric.SummaryDataPoint:
+			dp.SetStartTimestamp(shift(dp.StartTimestamp()))
+			dp.SetTimestamp(shift(dp.Timestamp()))
+		}
+		return nil
+	})
+}
+
+// exportData sends the data as the same export request as received by Server.
+func exportData(ctx context.Context, exporter Exporter, d probe.OTLPData) error {
+	switch d.Kind {
+	case probe.METRIC:
+		return exporter.exportMetrics(ctx, pmetricotlp.NewExportRequestFromMetrics(d.Metrics))
+	case probe.LOG:
+		return exporter.exportLogs(ctx, plogotlp.NewExportRequestFromLogs(d.Logs))
+	case probe.TRACE:
+		return exporter.exportTraces(ctx, ptraceotlp.NewExportRequestFromTraces(d.Traces))
+	}
+	return fmt.Errorf("unknown kind of signal %v", d.Kind)
+}
+
+// parseSpeed accepts a multiplier like 1x, 10x, 0.5x or max (as fast as
+// possible, returned as zero).
+func parseSpeed(s string) (float64, error) {
+	if s == "max" {
+		return 0, nil
+	}
+	v, err := strconv.ParseFloat(strings.TrimSuffix(s, "x"), 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid speed: %q", s)
+	}
+	return v, nil
+}
+
+// runReplay exports captured or dumped requests to an OTLP endpoint.
+func runReplay(args []string) {
+
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: otlprobe replay --to <endpoint> [options] <file or capture directory>...\n\n")
+		fs.PrintDefaults()
+	}
+	opts := newExporterOptions()
+	fs.StringVar(&opts.endpoint, "to", "", "endpoint receiving the requests, e.g. localhost:4317, grpc://collector:4317 or https://collector:4318")
+	opts.register(fs, "", "replayed requests")
+	speedPtr := fs.String("speed", "1x", "replay speed: 1x keeps the original timing, 10x is ten times faster, max sends as fast as possible")
+	shiftPtr := fs.Bool("shift-to-now", false, "shift timestamps, so the requests look like they were created now")
+	timeoutPtr := fs.Duration("timeout", 10*time.Second, "timeout of exported requests")
+	fs.Parse(args)
+	if opts.endpoint == "" || fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	speed, err := parseSpeed(*speedPtr)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	exporter, err := newExporter(*opts)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer exporter.close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	r := newReplayer(exporter, speed, *shiftPtr, *timeoutPtr)
+	ok := true
+	for _, path := range fs.Args() {
+		err := readOTLPFile(path, func(d probe.OTLPData) error {
+			if err := r.replay(ctx, d); err != nil && ctx.Err() == nil {
+				log.Printf("replay: %v", err)
+			}
+			return ctx.Err()
+		})
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				log.Println(err)
+			}
+			ok = false
+			break
+		}
+	}
+	fmt.Fprintf(os.Stderr, "replayed %d requests (%d signals), %d failed\n", r.requests, r.signals, r.failed)
+	if !ok || r.failed > 0 {
+		exporter.close()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tomplus/otlprobe/probe"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+func TestReplay(t *testing.T) {
+
+	_, upstream, grpcEndpoint, _ := newUpstream(t)
+
+	// three requests received a second apart, an hour ago
+	dir := t.TempDir()
+	c, err := newCapture(dir, defaultCaptureSegmentSize, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := time.Now().Add(-time.Hour)
+	for i := 0; i < 3; i++ {
+		request := newTestTraces()
+		sp := request.Traces().ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+		sp.SetStartTimestamp(pcommon.NewTimestampFromTime(received.Add(-time.Second)))
+		sp.SetEndTimestamp(pcommon.NewTimestampFromTime(received))
+		payload, _ := request.MarshalProto()
+		c.write(probe.TRACE, received, payload)
+		received = received.Add(time.Second)
+	}
+	c.close()
+
+	exporter, err := newExporter(ExporterOptions{endpoint: grpcEndpoint})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exporter.close()
+
+	r := newReplayer(exporter, 20, true, 5*time.Second)
+	start := time.Now()
+	err = readOTLPFile(dir, func(d probe.OTLPData) error {
+		return r.replay(context.Background(), d)
+	})
+	if err != nil || r.requests != 3 || r.signals != 3 || r.failed != 0 {
+		t.Fatalf("invalid replay => %v, %d, %d, %d", err, r.requests, r.signals, r.failed)
+	}
+	// 2 seconds at 20x speed
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("invalid timing => %v", elapsed)
+	}
+	// all requests are shifted by the same offset, so they are still a second apart
+	ends := make([]pcommon.Timestamp, 0)
+	for i := 0; i < 3; i++ {
+		s := waitForSignal(t, upstream)
+		end := s.Span.EndTimestamp().AsTime()
+		if time.Since(end) > time.Minute || s.Span.EndTimestamp()-s.Span.StartTimestamp() != pcommon.Timestamp(time.Second) {
+			t.Errorf("timestamps should be shifted to now => %v, %v", s.Span.StartTimestamp(), end)
+		}
+		ends = append(ends, s.Span.EndTimestamp())
+	}
+	if ends[1]-ends[0] != pcommon.Timestamp(time.Second) || ends[2]-ends[1] != pcommon.Timestamp(time.Second) {
+		t.Errorf("intervals between requests should be kept => %v", ends)
+	}
+
+	// as fast as possible keeps the timestamps
+	r = newReplayer(exporter, 0, false, 5*time.Second)
+	start = time.Now()
+	readOTLPFile(dir, func(d probe.OTLPData) error {
+		return r.replay(context.Background(), d)
+	})
+	if time.Since(start) > time.Second || r.requests != 3 {
+		t.Errorf("invalid replay as fast as possible => %v, %d", time.Since(start), r.requests)
+	}
+	if s := waitForSignal(t, upstream); time.Since(s.Span.EndTimestamp().AsTime()) < 59*time.Minute {
+		t.Errorf("timestamps should be kept => %v", s.Span.EndTimestamp())
+	}
+
+}
+
+func TestShiftTimestamps(t *testing.T) {
+
+	ld, td, md := newViewData()
+	lr := ld.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+	lr.SetObservedTimestamp(100)
+	dp := md.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0)
+	dp.SetTimestamp(50)
+	dp.Exemplars().AppendEmpty().SetTimestamp(40)
+
+	logs := probe.OTLPData{Kind: probe.LOG, Logs: ld}
+	if requestTime(logs) != pcommon.Timestamp(100).AsTime() {
+		t.Errorf("invalid requestTime() => %v", requestTime(logs))
+	}
+	shiftTimestamps(logs, 10)
+	if lr.Timestamp() != 0 || lr.ObservedTimestamp() != 110 {
+		t.Errorf("invalid log timestamps => %v, %v", lr.Timestamp(), lr.ObservedTimestamp())
+	}
+
+	traces := probe.OTLPData{Kind: probe.TRACE, Traces: td}
+	shiftTimestamps(traces, -1)
+	sp := td.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	if sp.StartTimestamp() != 0 || sp.EndTimestamp() != 1 {
+		t.Errorf("invalid span timestamps => %v, %v", sp.StartTimestamp(), sp.EndTimestamp())
+	}
+
+	shiftTimestamps(probe.OTLPData{Kind: probe.METRIC, Metrics: md}, 5)
+	if dp.StartTimestamp() != 0 || dp.Timestamp() != 55 || dp.Exemplars().At(0).Timestamp() != 45 {
+		t.Errorf("invalid data point timestamps => %v, %v", dp.Timestamp(), dp.Exemplars().At(0).Timestamp())
+	}
+
+}
+
+func TestParseSpeed(t *testing.T) {
+
+	for s, expected := range map[string]float64{"1x": 1, "10x": 10, "0.5x": 0.5, "2": 2, "max": 0} {
+		if v, err := parseSpeed(s); err != nil || v != expected {
+			t.Errorf("invalid speed %q => %v, %v", s, v, err)
+		}
+	}
+	for _, s := range []string{"", "fast", "0x", "-1x"} {
+		if _, err := parseSpeed(s); err == nil {
+			t.Errorf("speed %q should fail", s)
+		}
+	}
+
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+type TLSOptions struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	selfSigned   bool
+}
+
+func (o TLSOptions) enabled() bool {
+	return o.certFile != "" || o.keyFile != "" || o.clientCAFile != "" || o.selfSigned
+}
+
+// newServerTLSConfig builds the TLS configuration shared by the gRPC and HTTP
+// receivers. It returns nil when TLS is not requested. If a self-signed
+// certificate is generated, its SHA-256 fingerprint is returned as well.
+func newServerTLSConfig(o TLSOptions) (*tls.Config, string, error) {
+	if !o.enabled() {
+		return nil, "", nil
+	}
+
+	var cert tls.Certificate
+	var fingerprint string
+	var err error
+	switch {
+	case o.selfSigned && (o.certFile != "" || o.keyFile != ""):
+		return nil, "", errors.New("self-signed certificate cannot be combined with certificate/key files")
+	case o.selfSigned:
+		cert, err = generateSelfSignedCert(selfSignedHosts())
+		if err != nil {
+			return nil, "", fmt.Errorf("cannot generate self-signed certificate: %w", err)
+		}
+		fingerprint = certFingerprint(cert.Certificate[0])
+	case o.certFile == "" || o.keyFile == "":
+		return nil, "", errors.New("both certificate and key files are required")
+	default:
+		cert, err = tls.LoadX509KeyPair(o.certFile, o.keyFile)
+		if err != nil {
+			return nil, "", fmt.Errorf("cannot load certificate: %w", err)
+		}
+	}
+
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if o.clientCAFile != "" {
+		pool, err := loadCertPool(o.clientCAFile)
+		if err != nil {
+			return nil, "", err
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return cfg, fingerprint, nil
+}
+
+func loadCertPool(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no valid certificates found in %s", file)
+	}
+	return pool, nil
+}
+
+func selfSignedHosts() []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		hosts = append(hosts, hostname)
+	}
+	return hosts
+}
+
+func generateSelfSignedCert(hosts []string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	now := time.Now()
+	tmpl := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"otlprobe"}, CommonName: hosts[0]},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+// certFingerprint returns SHA-256 of DER encoded certificate in the usual
+// colon separated form (AB:CD:...).
+func certFingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// newClientTLSConfig builds TLS configuration used to connect to
+// an upstream endpoint (e.g. in the forwarding mode).
+func newClientTLSConfig(caFile string, certFile string, keyFile string, insecureSkipVerify bool) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+	if caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
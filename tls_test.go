@@ -0,0 +1,73 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writePEM(t *testing.T, dir string, name string, blockType string, der []byte) string {
+	path := filepath.Join(dir, name)
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewServerTLSConfig(t *testing.T) {
+
+	// disabled
+	cfg, fp, err := newServerTLSConfig(TLSOptions{})
+	if cfg != nil || fp != "" || err != nil {
+		t.Errorf("invalid newServerTLSConfig() => %v, %v, %v", cfg, fp, err)
+	}
+
+	// self-signed
+	cfg, fp, err = newServerTLSConfig(TLSOptions{selfSigned: true})
+	if err != nil || cfg == nil || len(fp) != 32*3-1 {
+		t.Fatalf("invalid newServerTLSConfig() => %v, %v, %v", cfg, fp, err)
+	}
+	if cfg.ClientAuth != tls.NoClientCert {
+		t.Errorf("invalid ClientAuth => %v", cfg.ClientAuth)
+	}
+	leaf, err := x509.ParseCertificate(cfg.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := leaf.VerifyHostname("localhost"); err != nil {
+		t.Errorf("invalid hostname => %v", err)
+	}
+	if certFingerprint(leaf.Raw) != fp {
+		t.Errorf("invalid fingerprint => %v", fp)
+	}
+
+	// files with client CA (mTLS)
+	dir := t.TempDir()
+	cert, _ := generateSelfSignedCert([]string{"localhost"})
+	key, _ := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	certFile := writePEM(t, dir, "cert.pem", "CERTIFICATE", cert.Certificate[0])
+	keyFile := writePEM(t, dir, "key.pem", "PRIVATE KEY", key)
+	cfg, fp, err = newServerTLSConfig(TLSOptions{certFile: certFile, keyFile: keyFile, clientCAFile: certFile})
+	if err != nil || cfg == nil || fp != "" {
+		t.Fatalf("invalid newServerTLSConfig() => %v, %v, %v", cfg, fp, err)
+	}
+	if cfg.ClientAuth != tls.RequireAndVerifyClientCert || cfg.ClientCAs == nil {
+		t.Errorf("invalid mTLS config => %v", cfg.ClientAuth)
+	}
+
+	// invalid combinations
+	if _, _, err = newServerTLSConfig(TLSOptions{certFile: certFile}); err == nil {
+		t.Errorf("missing key file should fail")
+	}
+	if _, _, err = newServerTLSConfig(TLSOptions{selfSigned: true, certFile: certFile, keyFile: keyFile}); err == nil {
+		t.Errorf("self-signed with files should fail")
+	}
+	if _, _, err = newServerTLSConfig(TLSOptions{selfSigned: true, clientCAFile: keyFile}); err == nil {
+		t.Errorf("invalid CA file should fail")
+	}
+
+}
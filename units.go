@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// byteSize is a flag value which accepts sizes like 512, 64KB, 20MiB or 1GB.
+type byteSize int64
+
+var byteSizeUnits = []struct {
+	suffix string
+	mult   int64
+}{
+	{"KIB", 1 << 10},
+	{"MIB", 1 << 20},
+	{"GIB", 1 << 30},
+	{"KB", 1000},
+	{"MB", 1000 * 1000},
+	{"GB", 1000 * 1000 * 1000},
+	{"K", 1 << 10},
+	{"M", 1 << 20},
+	{"G", 1 << 30},
+	{"B", 1},
+}
+
+func parseByteSize(s string) (byteSize, error) {
+	txt := strings.ToUpper(strings.TrimSpace(s))
+	mult := int64(1)
+	for _, u := range byteSizeUnits {
+		if strings.HasSuffix(txt, u.suffix) {
+			txt = strings.TrimSpace(strings.TrimSuffix(txt, u.suffix))
+			mult = u.mult
+			break
+		}
+	}
+	v, err := strconv.ParseFloat(txt, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid size: %q", s)
+	}
+	return byteSize(v * float64(mult)), nil
+}
+
+func (b *byteSize) String() string {
+	if b == nil {
+		return "0"
+	}
+	v := int64(*b)
+	switch {
+	case v >= 1<<30 && v%(1<<30) == 0:
+		return fmt.Sprintf("%dGiB", v>>30)
+	case v >= 1<<20 && v%(1<<20) == 0:
+		return fmt.Sprintf("%dMiB", v>>20)
+	case v >= 1<<10 && v%(1<<10) == 0:
+		return fmt.Sprintf("%dKiB", v>>10)
+	}
+	return strconv.FormatInt(v, 10)
+}
+
+func (b *byteSize) Set(s string) error {
+	v, err := parseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*b = v
+	return nil
+}
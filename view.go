@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tomplus/otlprobe/probe"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// runView browses OTLP files in the TUI without starting any listener.
+func runView(args []string) {
+
+	fs := flag.NewFlagSet("view", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: otlprobe view [options] <file or capture directory>...\n\n")
+		fmt.Fprintf(fs.Output(), "Reads OTLP JSON lines, length-prefixed OTLP protobuf and otlprobe captures.\n\n")
+		fs.PrintDefaults()
+	}
+	filterPtr := fs.String("filter", "", "show only matching signals, e.g. 'kind:log severity>=WARN'")
+	var bucketOpts BucketOptions
+	bucketOpts.register(fs)
+	var lintOpts LintOptions
+	lintOpts.register(fs)
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	bucket, err := bucketOpts.newBucket(fs)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	filter, err := parseQuery(*filterPtr)
+	if err != nil {
+		log.Fatalf("invalid filter: %v", err)
+	}
+	linter, err := lintOpts.newLinter()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for _, path := range fs.Args() {
+		if _, err := os.Stat(path); err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	chSignal := make(chan *probe.Signal)
+	server := probe.NewServer(0, 0, chSignal)
+	server.Linter = linter
+	runTUI(bucket, filter, chSignal, server.Faults, func(browser *Browser) {
+		// files are read only as fast as the browser shows them
+		browser.setHold(true)
+		go func() {
+			n := 0
+			for _, path := range fs.Args() {
+				err := readOTLPFile(path, func(d probe.OTLPData) error {
+					n++
+					return server.Process(context.Background(), d)
+				})
+				if err != nil {
+					browser.showMessage(err.Error())
+					return
+				}
+			}
+			browser.showMessage(fmt.Sprintf("read %d requests from %d files", n, fs.NArg()))
+		}()
+	})
+}
+
+// readOTLPFile calls fn for every request in the file, the format is
+// detected from the content (a protobuf frame can't start with '{' or the
+// capture magic, its length would exceed maxRecordSize). Directories are
+// read as otlprobe captures.
+func readOTLPFile(path string, fn func(d probe.OTLPData) error) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	decode := func(r CaptureRecord) error {
+		d, err := r.decode()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		return fn(d)
+	}
+	if fi.IsDir() {
+		return readCapture(path, decode)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	rd := bufio.NewReaderSize(f, 1<<16)
+	if magic, _ := rd.Peek(len(captureMagic)); string(magic) == captureMagic {
+		return readSegment(path, decode)
+	}
+	if first, err := firstByte(rd); err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	} else if first == '{' {
+		return readJSONLines(path, rd, fn)
+	}
+	return readProtoFrames(path, rd, fn)
+}
+
+// firstByte returns the first byte which is not a white space.
+func firstByte(rd *bufio.Reader) (byte, error) {
+	for {
+		b, err := rd.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			return b, rd.UnreadByte()
+		}
+	}
+}
+
+// readJSONLines reads one request per line, as written by the file exporter
+// of the collector.
+func readJSONLines(path string, rd *bufio.Reader, fn func(d probe.OTLPData) error) error {
+	for line := 1; ; line++ {
+		data, err := rd.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if data = bytes.TrimSpace(data); len(data) > 0 {
+			d, derr := decodeJSON(data)
+			if derr != nil {
+				return fmt.Errorf("%s:%d: %w", path, line, derr)
+			}
+			if ferr := fn(d); ferr != nil {
+				return ferr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+func decodeJSON(data []byte) (probe.OTLPData, error) {
+	kind, err := jsonKind(data)
+	if err != nil {
+		return probe.OTLPData{}, err
+	}
+	d := probe.OTLPData{Kind: kind}
+	switch kind {
+	case probe.METRIC:
+		d.Metrics, err = (&pmetric.JSONUnmarshaler{}).UnmarshalMetrics(data)
+	case probe.LOG:
+		d.Logs, err = (&plog.JSONUnmarshaler{}).UnmarshalLogs(data)
+	case probe.TRACE:
+		d.Traces, err = (&ptrace.JSONUnmarshaler{}).UnmarshalTraces(data)
+	}
+	return d, err
+}
+
+// jsonKind finds the kind of the request by its top-level field, usually
+// it's the first one, so the rest is not parsed.
+func jsonKind(data []byte) (probe.KindSignal, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return 0, errors.New("expected JSON object")
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return 0, err
+		}
+		switch t {
+		case "resourceMetrics", "resource_metrics":
+			return probe.METRIC, nil
+		case "resourceLogs", "resource_logs":
+			return probe.LOG, nil
+		case "resourceSpans", "resource_spans":
+			return probe.TRACE, nil
+		}
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return 0, err
+		}
+	}
+	return 0, errors.New("expected resourceMetrics, resourceLogs or resourceSpans")
+}
+
+// readProtoFrames reads requests prefixed with their length (uint32, big
+// endian), as written by the file exporter of the collector.
+func readProtoFrames(path string, rd *bufio.Reader, fn func(d probe.OTLPData) error) error {
+	header := make([]byte, 4)
+	for frame := 1; ; frame++ {
+		if _, err := io.ReadFull(rd, header); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("%s: frame %d: %w", path, frame, err)
+		}
+		length := binary.BigEndian.Uint32(header)
+		if length > maxRecordSize {
+			return fmt.Errorf("%s: frame %d: invalid length %d", path, frame, length)
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(rd, data); err != nil {
+			return fmt.Errorf("%s: frame %d: %w", path, frame, err)
+		}
+		d, err := decodeProto(data)
+		if err != nil {
+			return fmt.Errorf("%s: frame %d: %w", path, frame, err)
+		}
+		if err := fn(d); err != nil {
+			return err
+		}
+	}
+}
+
+// decodeProto guesses the kind of the request. The messages have the same
+// structure at the top level and the records differ in types of fields, so
+// usually only the right kind is unmarshalled without error. A metric with
+// 16 bytes long name is still a valid span, but with an unreadable name.
+func decodeProto(data []byte) (probe.OTLPData, error) {
+	var empty *probe.OTLPData
+	if td, err := (&ptrace.ProtoUnmarshaler{}).UnmarshalTraces(data); err == nil && plausibleSpans(td) {
+		if td.SpanCount() > 0 {
+			return probe.OTLPData{Kind: probe.TRACE, Traces: td}, nil
+		}
+		empty = &probe.OTLPData{Kind: probe.TRACE, Traces: td}
+	}
+	if ld, err := (&plog.ProtoUnmarshaler{}).UnmarshalLogs(data); err == nil && ld.LogRecordCount() > 0 {
+		return probe.OTLPData{Kind: probe.LOG, Logs: ld}, nil
+	}
+	if md, err := (&pmetric.ProtoUnmarshaler{}).UnmarshalMetrics(data); err == nil && md.DataPointCount() > 0 {
+		return probe.OTLPData{Kind: probe.METRIC, Metrics: md}, nil
+	}
+	if empty != nil {
+		// a request without records
+		return *empty, nil
+	}
+	return probe.OTLPData{}, errors.New("not an OTLP request")
+}
+
+// plausibleSpans checks that spans have IDs and readable names.
+func plausibleSpans(td ptrace.Traces) bool {
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		sss := rss.At(i).ScopeSpans()
+		for j := 0; j < sss.Len(); j++ {
+			spans := sss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				sp := spans.At(k)
+				if sp.TraceID().IsEmpty() || sp.SpanID().IsEmpty() || !utf8.ValidString(sp.Name()) {
+					return false
+				}
+				if strings.IndexFunc(sp.Name(), unicode.IsControl) >= 0 {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tomplus/otlprobe/probe"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// newViewData returns a request with two log records, one with a span and
+// one with a gauge.
+func newViewData() (plog.Logs, ptrace.Traces, pmetric.Metrics) {
+	ld := plog.NewLogs()
+	lrs := ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	lrs.AppendEmpty().Body().SetStr("one")
+	lrs.AppendEmpty().Body().SetStr("two")
+
+	td := ptrace.NewTraces()
+	sp := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	sp.SetName("GET /")
+	sp.SetTraceID([16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+	sp.SetSpanID([8]byte{1, 2, 3, 4, 5, 6, 7, 8})
+	sp.SetStartTimestamp(1)
+	sp.SetEndTimestamp(2)
+
+	md := pmetric.NewMetrics()
+	m := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+	m.SetName("queue.size.total")
+	m.SetEmptyGauge().DataPoints().AppendEmpty().SetIntValue(42)
+	return ld, td, md
+}
+
+func readViewFile(t *testing.T, path string) []*probe.Signal {
+	signals := make([]*probe.Signal, 0)
+	err := readOTLPFile(path, func(d probe.OTLPData) error {
+		return d.Signals(func(s *probe.Signal) error {
+			signals = append(signals, s)
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("invalid file %s => %v", path, err)
+	}
+	return signals
+}
+
+func checkViewSignals(t *testing.T, signals []*probe.Signal) {
+	summaries := []string{}
+	for _, s := range signals {
+		summaries = append(summaries, s.Summary())
+	}
+	if len(signals) != 4 || signals[0].Kind != probe.LOG || signals[1].Summary() != ": two" ||
+		signals[2].Span.Name() != "GET /" || signals[3].Summary() != "queue.size.total=42" {
+		t.Errorf("invalid signals => %v", summaries)
+	}
+}
+
+func TestViewJSONLines(t *testing.T) {
+
+	ld, td, md := newViewData()
+	var buf bytes.Buffer
+	data, _ := (&plog.JSONMarshaler{}).MarshalLogs(ld)
+	buf.Write(data)
+	buf.WriteString("\n\n")
+	data, _ = (&ptrace.JSONMarshaler{}).MarshalTraces(td)
+	buf.Write(data)
+	buf.WriteString("\n")
+	data, _ = (&pmetric.JSONMarshaler{}).MarshalMetrics(md)
+	buf.Write(data)
+
+	path := filepath.Join(t.TempDir(), "dump.json")
+	os.WriteFile(path, buf.Bytes(), 0o644)
+	checkViewSignals(t, readViewFile(t, path))
+
+	os.WriteFile(path, []byte(`{"resourceLogs":[]}`+"\n"+`{"foo":1}`), 0o644)
+	err := readOTLPFile(path, func(d probe.OTLPData) error { return nil })
+	if err == nil || err.Error() != path+":2: expected resourceMetrics, resourceLogs or resourceSpans" {
+		t.Errorf("invalid error => %v", err)
+	}
+
+}
+
+func TestViewProtoFrames(t *testing.T) {
+
+	ld, td, md := newViewData()
+	var buf bytes.Buffer
+	frame := func(data []byte) {
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(len(data))))
+		buf.Write(data)
+	}
+	data, _ := (&plog.ProtoMarshaler{}).MarshalLogs(ld)
+	frame(data)
+	data, _ = (&ptrace.ProtoMarshaler{}).MarshalTraces(td)
+	frame(data)
+	data, _ = (&pmetric.ProtoMarshaler{}).MarshalMetrics(md)
+	frame(data)
+
+	path := filepath.Join(t.TempDir(), "dump.pb")
+	os.WriteFile(path, buf.Bytes(), 0o644)
+	checkViewSignals(t, readViewFile(t, path))
+
+	os.WriteFile(path, buf.Bytes()[:buf.Len()-2], 0o644)
+	if err := readOTLPFile(path, func(d probe.OTLPData) error { return nil }); err == nil {
+		t.Errorf("truncated frame should fail")
+	}
+
+}
+
+func TestViewCapture(t *testing.T) {
+
+	dir := t.TempDir()
+	c, err := newCapture(dir, defaultCaptureSegmentSize, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ld, td, md := newViewData()
+	for _, r := range []struct {
+		kind probe.KindSignal
+		data []byte
+	}{
+		{probe.LOG, must((&plog.ProtoMarshaler{}).MarshalLogs(ld))},
+		{probe.TRACE, must((&ptrace.ProtoMarshaler{}).MarshalTraces(td))},
+		{probe.METRIC, must((&pmetric.ProtoMarshaler{}).MarshalMetrics(md))},
+	} {
+		c.write(r.kind, time.Now(), r.data)
+	}
+	c.close()
+
+	checkViewSignals(t, readViewFile(t, dir))
+	segments, _ := captureSegments(dir)
+	checkViewSignals(t, readViewFile(t, segments[0].path))
+
+}
+
+func must(data []byte, err error) []byte {
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
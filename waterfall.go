@@ -0,0 +1,359 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/tomplus/otlprobe/probe"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// Waterfall shows spans of a single trace as a parent/child tree with
+// duration bars on a shared timeline.
+type Waterfall struct {
+	screen        tcell.Screen
+	popUp         *PopUp
+	visible       bool
+	traceID       pcommon.TraceID
+	rows          []spanRow
+	spans         int
+	missing       int
+	start         pcommon.Timestamp
+	end           pcommon.Timestamp
+	cursor        int
+	offset        int
+	textStyle     tcell.Style
+	selectedStyle tcell.Style
+	headerStyle   tcell.Style
+	barStyle      tcell.Style
+	errorBarStyle tcell.Style
+	missingStyle  tcell.Style
+}
+
+// spanNode is a span in the trace tree. Nodes without signal are placeholders
+// for parents which have not been received.
+type spanNode struct {
+	signal   *probe.Signal
+	spanID   pcommon.SpanID
+	start    pcommon.Timestamp
+	end      pcommon.Timestamp
+	orphan   bool
+	children []*spanNode
+}
+
+type spanRow struct {
+	node  *spanNode
+	depth int
+}
+
+func newWaterfall(screen tcell.Screen, popUp *PopUp) *Waterfall {
+	w := Waterfall{
+		screen:        screen,
+		popUp:         popUp,
+		textStyle:     tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack),
+		selectedStyle: tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite).Bold(true),
+		headerStyle:   tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorLightCyan),
+		barStyle:      tcell.StyleDefault.Foreground(tcell.ColorDarkCyan).Background(tcell.ColorBlack),
+		errorBarStyle: tcell.StyleDefault.Foreground(tcell.ColorRed).Background(tcell.ColorBlack),
+		missingStyle:  tcell.StyleDefault.Foreground(tcell.ColorDarkGray).Background(tcell.ColorBlack),
+	}
+	return &w
+}
+
+// traceSpans collects buffered spans of the trace, oldest first.
+func traceSpans(bucket probe.Bucket, traceID pcommon.TraceID) []*probe.Signal {
+	spans := make([]*probe.Signal, 0)
+	for i := bucket.Len() - 1; i >= 0; i-- {
+		if ok, s := bucket.Get(i); ok && s.Kind == probe.TRACE && s.Span.TraceID() == traceID {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+// show collects buffered spans with the same trace ID as the selected one.
+func (waterfall *Waterfall) show(bucket probe.Bucket, selected *probe.Signal) {
+	traceID := selected.Span.TraceID()
+	roots := buildSpanTree(traceSpans(bucket, traceID))
+	waterfall.traceID = traceID
+	waterfall.rows = spanRows(roots)
+	waterfall.spans = 0
+	waterfall.missing = 0
+	waterfall.cursor = 0
+	waterfall.offset = 0
+	for i, row := range waterfall.rows {
+		if row.node.signal == nil {
+			waterfall.missing++
+			continue
+		}
+		waterfall.spans++
+		if row.node.signal == selected {
+			waterfall.cursor = i
+		}
+	}
+	waterfall.start, waterfall.end = 0, 0
+	for i, n := range roots {
+		if i == 0 || n.start < waterfall.start {
+			waterfall.start = n.start
+		}
+		waterfall.end = max(waterfall.end, n.end)
+	}
+	waterfall.visible = true
+}
+
+// buildSpanTree links spans to their parents. Spans with parents which are not
+// in the buffer are marked as orphans and grouped under a placeholder of the
+// missing parent. Siblings are ordered by start time.
+func buildSpanTree(spans []*probe.Signal) []*spanNode {
+	nodes := make(map[pcommon.SpanID]*spanNode, len(spans))
+	ordered := make([]*spanNode, 0, len(spans))
+	for _, s := range spans {
+		id := s.Span.SpanID()
+		if _, ok := nodes[id]; ok {
+			// the same span received more than once
+			continue
+		}
+		n := &spanNode{signal: s, spanID: id, start: s.Span.StartTimestamp(), end: max(s.Span.EndTimestamp(), s.Span.StartTimestamp())}
+		nodes[id] = n
+		ordered = append(ordered, n)
+	}
+
+	parents := make(map[*spanNode]*spanNode, len(ordered))
+	for _, n := range ordered {
+		if p, ok := nodes[n.signal.Span.ParentSpanID()]; ok && p != n {
+			parents[n] = p
+		}
+	}
+	// break cycles, so the tree can be walked
+	for _, n := range ordered {
+		for p, steps := parents[n], 0; p != nil && steps < len(ordered); p, steps = parents[p], steps+1 {
+			if p == n {
+				delete(parents, n)
+				break
+			}
+		}
+	}
+
+	roots := make([]*spanNode, 0)
+	missing := make(map[pcommon.SpanID]*spanNode)
+	for _, n := range ordered {
+		parentID := n.signal.Span.ParentSpanID()
+		if p, ok := parents[n]; ok {
+			p.children = append(p.children, n)
+			continue
+		}
+		if parentID.IsEmpty() {
+			roots = append(roots, n)
+			continue
+		}
+		n.orphan = true
+		if _, ok := nodes[parentID]; ok {
+			// part of a cycle
+			roots = append(roots, n)
+			continue
+		}
+		p, ok := missing[parentID]
+		if !ok {
+			p = &spanNode{spanID: parentID, start: n.start, end: n.end}
+			missing[parentID] = p
+			roots = append(roots, p)
+		}
+		p.start = min(p.start, n.start)
+		p.end = max(p.end, n.end)
+		p.children = append(p.children, n)
+	}
+
+	sortSpanNodes(roots)
+	return roots
+}
+
+func sortSpanNodes(nodes []*spanNode) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].start < nodes[j].start
+	})
+	for _, n := range nodes {
+		sortSpanNodes(n.children)
+	}
+}
+
+// spanRows flattens the tree in depth-first order.
+func spanRows(roots []*spanNode) []spanRow {
+	res := make([]spanRow, 0)
+	var walk func(nodes []*spanNode, depth int)
+	walk = func(nodes []*spanNode, depth int) {
+		for _, n := range nodes {
+			res = append(res, spanRow{node: n, depth: depth})
+			walk(n.children, depth+1)
+		}
+	}
+	walk(roots, 0)
+	return res
+}
+
+func (row spanRow) label() string {
+	indent := strings.Repeat("  ", row.depth)
+	if row.node.signal == nil {
+		return fmt.Sprintf("%s? missing span %v", indent, row.node.spanID)
+	}
+	name := row.node.signal.Span.Name()
+	if service, ok := row.node.signal.Resource.Attributes().Get("service.name"); ok {
+		name = service.AsString() + ": " + name
+	}
+	if row.node.orphan {
+		return indent + "⚠ " + name
+	}
+	return indent + name
+}
+
+// shortDuration rounds the duration to 3 fractional digits of its unit.
+func shortDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(time.Microsecond).String()
+	}
+	return d.String()
+}
+
+func (waterfall *Waterfall) drawText(x int, y int, width int, style tcell.Style, text string) {
+	for col, i := x, 0; col < x+width; col++ {
+		r := ' '
+		if i < len(text) {
+			var s int
+			r, s = utf8.DecodeRuneInString(text[i:])
+			i += s
+		}
+		waterfall.screen.SetContent(col, y, r, nil, style)
+	}
+}
+
+// bar returns columns of the bar for the given time range.
+func (waterfall *Waterfall) bar(start, end pcommon.Timestamp, width int) (int, int) {
+	total := float64(waterfall.end - waterfall.start)
+	if total <= 0 {
+		return 0, width
+	}
+	x0 := int(math.Floor(float64(start-waterfall.start) / total * float64(width)))
+	x1 := int(math.Ceil(float64(end-waterfall.start) / total * float64(width)))
+	x0 = max(min(x0, width-1), 0)
+	x1 = max(min(x1, width), x0+1)
+	return x0, x1
+}
+
+func (waterfall *Waterfall) refresh() {
+
+	if !waterfall.visible {
+		return
+	}
+
+	w, h := waterfall.screen.Size()
+	total := time.Duration(waterfall.end - waterfall.start)
+
+	header := fmt.Sprintf(" Trace %v  spans: %d  duration: %s", waterfall.traceID, waterfall.spans, shortDuration(total))
+	if waterfall.missing > 0 {
+		header += fmt.Sprintf("  missing parents: %d", waterfall.missing)
+	}
+	waterfall.drawText(0, 0, w, waterfall.headerStyle, header)
+
+	labelWidth := min(max(w*2/5, 20), w)
+	durationWidth := 11
+	barX := labelWidth + durationWidth
+	barWidth := w - barX - 1
+
+	// time axis
+	waterfall.drawText(0, 1, w, waterfall.textStyle, "")
+	if barWidth > 0 {
+		for _, tick := range []float64{0, 0.5, 1} {
+			text := shortDuration(time.Duration(float64(total) * tick))
+			x := barX + int(float64(barWidth)*tick) - int(float64(len(text))*tick)
+			waterfall.drawText(x, 1, len(text), waterfall.textStyle, text)
+		}
+	}
+
+	height := h - 3
+	waterfall.cursor = max(min(waterfall.cursor, len(waterfall.rows)-1), 0)
+	if waterfall.cursor < waterfall.offset {
+		waterfall.offset = waterfall.cursor
+	}
+	if height > 0 && waterfall.cursor >= waterfall.offset+height {
+		waterfall.offset = waterfall.cursor - height + 1
+	}
+
+	for i, y := waterfall.offset, 2; y < h-1; i, y = i+1, y+1 {
+		if i >= len(waterfall.rows) {
+			waterfall.drawText(0, y, w, waterfall.textStyle, "")
+			continue
+		}
+		row := waterfall.rows[i]
+		style := waterfall.textStyle
+		if i == waterfall.cursor {
+			style = waterfall.selectedStyle
+		}
+		label := row.label()
+		if utf8.RuneCountInString(label) > labelWidth-1 {
+			label = string([]rune(label)[:max(labelWidth-2, 0)]) + "…"
+		}
+		waterfall.drawText(0, y, labelWidth, style, label)
+		duration := shortDuration(time.Duration(row.node.end - row.node.start))
+		waterfall.drawText(labelWidth, y, durationWidth, style, fmt.Sprintf("%*s ", durationWidth-1, duration))
+
+		if barWidth <= 0 {
+			continue
+		}
+		barStyle, r := waterfall.barStyle, '█'
+		if row.node.signal == nil {
+			barStyle, r = waterfall.missingStyle, '┄'
+		} else if row.node.signal.Span.Status().Code() == ptrace.StatusCodeError {
+			barStyle = waterfall.errorBarStyle
+		}
+		x0, x1 := waterfall.bar(row.node.start, row.node.end, barWidth)
+		for col := 0; col < w-barX; col++ {
+			if col >= x0 && col < x1 {
+				waterfall.screen.SetContent(barX+col, y, r, nil, barStyle)
+			} else {
+				waterfall.screen.SetContent(barX+col, y, ' ', nil, waterfall.textStyle)
+			}
+		}
+	}
+
+}
+
+func (waterfall *Waterfall) eventKey(ev *tcell.EventKey) bool {
+
+	_, h := waterfall.screen.Size()
+	page := max(h-4, 1)
+
+	switch ev.Key() {
+	case tcell.KeyEscape:
+		waterfall.visible = false
+	case tcell.KeyUp:
+		waterfall.cursor = max(waterfall.cursor-1, 0)
+	case tcell.KeyDown:
+		waterfall.cursor = min(waterfall.cursor+1, len(waterfall.rows)-1)
+	case tcell.KeyPgUp:
+		waterfall.cursor = max(waterfall.cursor-page, 0)
+	case tcell.KeyPgDn:
+		waterfall.cursor = min(waterfall.cursor+page, len(waterfall.rows)-1)
+	case tcell.KeyHome:
+		waterfall.cursor = 0
+	case tcell.KeyEnd:
+		waterfall.cursor = len(waterfall.rows) - 1
+	case tcell.KeyEnter:
+		if waterfall.cursor >= 0 && waterfall.cursor < len(waterfall.rows) {
+			if s := waterfall.rows[waterfall.cursor].node.signal; s != nil {
+				waterfall.popUp.show(s.Properties())
+			}
+		}
+	default:
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tomplus/otlprobe/probe"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func newTestSpan(id byte, parent byte, start int, end int) *probe.Signal {
+	sp := ptrace.NewSpan()
+	sp.SetName(string('a' + rune(id) - 1))
+	sp.SetTraceID([16]byte{1})
+	sp.SetSpanID([8]byte{id})
+	if parent != 0 {
+		sp.SetParentSpanID([8]byte{parent})
+	}
+	sp.SetStartTimestamp(pcommon.Timestamp(start))
+	sp.SetEndTimestamp(pcommon.Timestamp(end))
+	return &probe.Signal{Kind: probe.TRACE, Span: sp, Resource: pcommon.NewResource()}
+}
+
+func TestBuildSpanTree(t *testing.T) {
+
+	spans := []*probe.Signal{
+		newTestSpan(3, 1, 20, 30),
+		newTestSpan(2, 1, 10, 20),
+		newTestSpan(1, 0, 0, 100),
+		newTestSpan(2, 1, 10, 20), // duplicate
+		newTestSpan(5, 9, 40, 50), // orphan
+		newTestSpan(6, 9, 35, 45), // orphan with the same missing parent
+		newTestSpan(7, 8, 60, 70), // cycle
+		newTestSpan(8, 7, 65, 66), // cycle
+		newTestSpan(4, 3, 25, 20), // end before start
+	}
+
+	rows := spanRows(buildSpanTree(spans))
+	expected := []string{"a", "  b", "  c", "    d", "? missing span 0900000000000000", "  ⚠ f", "  ⚠ e", "⚠ g", "  h"}
+	if len(rows) != len(expected) {
+		t.Fatalf("invalid number of rows => %d", len(rows))
+	}
+	for i, row := range rows {
+		if row.label() != expected[i] {
+			t.Errorf("invalid row %d => %q", i, row.label())
+		}
+	}
+
+	missing := rows[4].node
+	if missing.signal != nil || missing.start != 35 || missing.end != 50 {
+		t.Errorf("invalid missing parent => %v, %v-%v", missing.signal, missing.start, missing.end)
+	}
+	if rows[3].node.end != rows[3].node.start {
+		t.Errorf("invalid end of span => %v", rows[3].node.end)
+	}
+
+}
+
+func TestWaterfallBar(t *testing.T) {
+
+	waterfall := Waterfall{start: 100, end: 200}
+	if x0, x1 := waterfall.bar(100, 200, 50); x0 != 0 || x1 != 50 {
+		t.Errorf("invalid bar => %d, %d", x0, x1)
+	}
+	if x0, x1 := waterfall.bar(150, 150, 50); x0 != 25 || x1 != 26 {
+		t.Errorf("invalid bar => %d, %d", x0, x1)
+	}
+	if s := shortDuration(1234567 * time.Nanosecond); s != "1.235ms" {
+		t.Errorf("invalid shortDuration() => %v", s)
+	}
+
+}
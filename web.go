@@ -0,0 +1,22 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+// webHandler serves the embedded web UI and the query API it's built on.
+func webHandler(api *API) http.Handler {
+	static, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		panic(err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/api/", api.handler())
+	mux.Handle("/", http.FileServerFS(static))
+	return mux
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebHandler(t *testing.T) {
+
+	api, _ := newTestAPI(t)
+	srv := httptest.NewServer(webHandler(api))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "<title>otlprobe</title>") {
+		t.Errorf("invalid index => %v, %v", resp.Status, len(body))
+	}
+
+	var signals []apiSectionSignal
+	if code := getJSON(t, srv.URL+"/api/signals?sections=true&kind=log", &signals); code != http.StatusOK ||
+		len(signals) != 1 || signals[0].Level != "error" || signals[0].Sections[0].Name != "Record" {
+		t.Errorf("invalid signals => %v, %+v", code, signals)
+	}
+
+}